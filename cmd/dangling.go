@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/manthan-parmar-1998/k8s-pruner/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+var danglingCmd = &cobra.Command{
+	Use:   "dangling",
+	Short: "List references to ConfigMaps, Secrets, PVCs and ServiceAccounts that don't exist",
+	Long: `List the Pods, workloads and ServiceAccounts that reference ConfigMaps, Secrets,
+PVCs or ServiceAccounts that don't exist. Such Pods are stuck, e.g. in
+CreateContainerConfigError, or will break on their next restart. References
+marked optional tolerate a missing object and are only listed with
+--include-optional.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Create resource detector
+		detector, err := newDetector()
+		if err != nil {
+			return err
+		}
+
+		// Find references to missing objects
+		refs, err := detector.DanglingReferences(namespace, includeOptional)
+		if err != nil {
+			return fmt.Errorf("error finding dangling references: %v", err)
+		}
+
+		return utils.OutputDanglingReferences(refs, output)
+	},
+}
+
+func init() {
+	danglingCmd.Flags().BoolVar(&includeOptional, "include-optional", false, "Also list optional references to missing objects")
+}
@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/manthan-parmar-1998/k8s-pruner/pkg/backup"
+	"github.com/manthan-parmar-1998/k8s-pruner/pkg/resources"
+)
+
+// checkBackupFlags rejects backup options given without --backup, invalid
+// recipients and encrypting to a directory, before anything is prompted for
+func checkBackupFlags() error {
+	if backupPath == "" && (len(backupRecipients) > 0 || redactSecretData) {
+		return fmt.Errorf("--backup-recipient and --redact-secret-data need --backup")
+	}
+	if len(backupRecipients) == 0 {
+		return nil
+	}
+	if _, err := backup.ParseRecipients(backupRecipients); err != nil {
+		return err
+	}
+	if !backup.IsTarball(backupPath) {
+		return fmt.Errorf("encrypted backups must be tarballs, e.g. --backup %s.tar.gz.age", strings.TrimSuffix(backupPath, "/"))
+	}
+	return nil
+}
+
+// checkDeletionLimits refuses to delete results if that exceeds
+// --max-deletions or --max-deletions-percent, unless --i-know-what-im-doing
+// is set, in which case it writes a warning to warn
+func checkDeletionLimits(detector *resources.ResourceDetector, results []resources.ResourceList, warn io.Writer) error {
+	violations, err := detector.CheckDeletionLimits(results, namespace, resources.DeletionLimits{
+		MaxDeletions: maxDeletions,
+		MaxPercent:   maxDeletionsPercent,
+	})
+	if err != nil {
+		return err
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+
+	message := "deletion limits exceeded:\n  " + strings.Join(violations, "\n  ")
+	if !iKnowWhatImDoing {
+		return fmt.Errorf("refusing to prune, %s\npass --i-know-what-im-doing to prune anyway", message)
+	}
+	fmt.Fprintf(warn, "\nWarning: %s\n", message)
+	return nil
+}
+
+// deleteSteps configures deleteCandidates
+type deleteSteps struct {
+	windows resources.TimeWindows
+	options resources.PruneOptions
+	// verify re-runs detection right before deleting. It is off for a
+	// reviewed plan, whose preconditions catch any change instead.
+	verify bool
+	// confirm, if set, is asked once the limits are checked; false stops
+	// before anything is deleted
+	confirm func() (bool, error)
+	// backupPath is where to back up to, if anywhere
+	backupPath string
+	// warn receives warnings and the backup summary
+	warn io.Writer
+}
+
+// deleteCandidates is the one path every command deletes through. It checks
+// the deletion limits, asks for confirmation, re-verifies that results are
+// still unused, backs them up and deletes them. It returns nil
+// if the deletion wasn't confirmed.
+func deleteCandidates(detector *resources.ResourceDetector, results []resources.ResourceList, steps deleteSteps) (*resources.DeletionReport, error) {
+	if err := checkDeletionLimits(detector, results, steps.warn); err != nil {
+		return nil, err
+	}
+
+	if steps.confirm != nil {
+		confirmed, err := steps.confirm()
+		if err != nil || !confirmed {
+			return nil, err
+		}
+	}
+
+	// The confirmation may have taken a while, so check again that nothing
+	// started using the candidates in the meantime
+	var skipped []resources.DeletionResult
+	if steps.verify {
+		var err error
+		results, skipped, err = detector.VerifyStillUnused(results, namespace, steps.windows, labels)
+		if err != nil {
+			return nil, fmt.Errorf("error re-verifying unused resources: %v", err)
+		}
+	}
+
+	// Open the backup every manifest is written to before deleting. A
+	// server dry run deletes nothing, so there is nothing to back up.
+	opts := steps.options
+	if steps.backupPath != "" && opts.DryRun {
+		fmt.Fprintln(steps.warn, "Skipping --backup, a server dry run deletes nothing")
+	}
+	if steps.backupPath != "" && !opts.DryRun {
+		recipients, err := backup.ParseRecipients(backupRecipients)
+		if err != nil {
+			return nil, err
+		}
+		opts.Backup, err = backup.Create(steps.backupPath, backup.Options{
+			Recipients:       recipients,
+			RedactSecretData: redactSecretData,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Delete resources, carrying on past failures
+	report := detector.DeleteUnusedResources(results, opts)
+	report.Results = append(skipped, report.Results...)
+
+	// DeleteUnusedResources closed the backup before deleting; a failure
+	// to close shows in the report as well
+	if opts.Backup != nil {
+		if err := opts.Backup.Close(); err != nil {
+			fmt.Fprintf(steps.warn, "Error writing the backup, nothing was deleted: %v\n", err)
+		} else {
+			fmt.Fprintf(steps.warn, "Backed up %d resources to %s\n", opts.Backup.Count(), steps.backupPath)
+		}
+	}
+
+	return &report, nil
+}
+
+// numberedBackupPath returns path for the first backup of a session and adds
+// -n before the extension for the nth, since a backup is never overwritten
+func numberedBackupPath(path string, n int) string {
+	if path == "" || n <= 1 {
+		return path
+	}
+
+	path = strings.TrimSuffix(path, "/")
+	ext := ""
+	if backup.IsTarball(path) {
+		for _, suffix := range []string{".tar.gz", ".tgz", ".tar"} {
+			if i := strings.LastIndex(path, suffix); i >= 0 {
+				ext = path[i:]
+				break
+			}
+		}
+	}
+	return fmt.Sprintf("%s-%d%s", strings.TrimSuffix(path, ext), n, ext)
+}
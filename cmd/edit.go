@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/manthan-parmar-1998/k8s-pruner/pkg/resources"
+	"github.com/manthan-parmar-1998/k8s-pruner/pkg/utils"
+)
+
+// editHeader explains the candidate list opened by prune --edit
+const editHeader = `# Resources to prune. Delete the lines of the resources to keep; the
+# remaining ones are deleted once you save and quit. Changing a line aborts
+# the prune, and so does an empty list.
+#
+# kind namespace/name  # reason, age
+`
+
+// editEntry is one line of the candidate list
+type editEntry struct {
+	resourceType string
+	item         resources.ResourceItem
+}
+
+// editCandidates lets the user trim results in $VISUAL or $EDITOR and returns
+// what is left
+func editCandidates(results []resources.ResourceList) ([]resources.ResourceList, error) {
+	text, entries := formatEditList(results)
+
+	file, err := os.CreateTemp("", "k8s-pruner-*.txt")
+	if err != nil {
+		return nil, fmt.Errorf("error creating candidate list: %v", err)
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := file.WriteString(text); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("error writing candidate list: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		return nil, fmt.Errorf("error writing candidate list: %v", err)
+	}
+
+	if err := runEditor(file.Name()); err != nil {
+		return nil, err
+	}
+
+	edited, err := os.ReadFile(file.Name())
+	if err != nil {
+		return nil, fmt.Errorf("error reading candidate list: %v", err)
+	}
+	return parseEditList(string(edited), entries)
+}
+
+// formatEditList writes one line per candidate and returns the entries by line
+func formatEditList(results []resources.ResourceList) (string, map[string]editEntry) {
+	var b strings.Builder
+	b.WriteString(editHeader)
+
+	entries := make(map[string]editEntry)
+	for _, resourceList := range results {
+		kindName := resourceList.ResourceType
+		if kind, ok := resources.KindForResourceType(resourceList.ResourceType); ok {
+			kindName = kind.Name
+			if kind.Singular != "" {
+				kindName = kind.Singular
+			}
+		}
+
+		b.WriteString("\n")
+		for _, item := range resourceList.Items {
+			name := item.Name
+			if item.Namespace != "" {
+				name = item.Namespace + "/" + item.Name
+			}
+
+			comment := utils.FormatAge(time.Since(item.CreationTimestamp))
+			if item.Reason != "" {
+				comment = item.Reason + ", " + comment
+			}
+
+			line := fmt.Sprintf("%s %s  # %s", kindName, name, comment)
+			entries[line] = editEntry{resourceType: resourceList.ResourceType, item: item}
+			b.WriteString(line + "\n")
+		}
+	}
+
+	return b.String(), entries
+}
+
+// parseEditList returns the candidates whose lines are left in text. Every
+// line must be one of the original lines unchanged.
+func parseEditList(text string, entries map[string]editEntry) ([]resources.ResourceList, error) {
+	var results []resources.ResourceList
+	index := make(map[string]int)
+	seen := make(map[string]bool)
+
+	for n, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+
+		entry, ok := entries[line]
+		if !ok {
+			return nil, fmt.Errorf("line %d was changed or isn't a candidate: %q; nothing was pruned", n+1, line)
+		}
+		if seen[line] {
+			return nil, fmt.Errorf("line %d is listed twice: %q; nothing was pruned", n+1, line)
+		}
+		seen[line] = true
+
+		i, ok := index[entry.resourceType]
+		if !ok {
+			i = len(results)
+			index[entry.resourceType] = i
+			results = append(results, resources.ResourceList{ResourceType: entry.resourceType, Items: []resources.ResourceItem{}})
+		}
+		results[i].Items = append(results[i].Items, entry.item)
+	}
+
+	return results, nil
+}
+
+// runEditor opens path in the user's editor and waits for it to exit
+func runEditor(path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	// Through the shell, so editors with arguments like "code --wait" work
+	cmd := exec.Command("sh", "-c", editor+` "$1"`, "sh", path)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("editor %q failed: %v; nothing was pruned", editor, err)
+	}
+	return nil
+}
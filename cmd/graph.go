@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/manthan-parmar-1998/k8s-pruner/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+var graphCmd = &cobra.Command{
+	Use:   "graph",
+	Short: "Export the graph of references between resources",
+	Long: `Export the graph of references between workloads, Pods, ConfigMaps, Secrets,
+PVCs, ServiceAccounts and Services, as Graphviz dot (the default), Mermaid or
+JSON. Edges are labeled by how the reference is made: volume, env, envFrom,
+imagePullSecret, serviceAccount, secret, or selector for Services. Unused
+ConfigMaps, Secrets, PVCs and Pods are highlighted, and referenced objects
+that don't exist are shown as missing.`,
+	Example: `  k8s-pruner graph -n team-a | dot -Tsvg > team-a.svg
+  k8s-pruner graph -n team-a -o mermaid`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Create resource detector
+		detector, err := newDetector()
+		if err != nil {
+			return err
+		}
+
+		// Parse the creation time window for each type
+		windows, err := utils.ParseTimeWindows(age, newerThan, before)
+		if err != nil {
+			return err
+		}
+
+		graph, err := detector.Graph(namespace)
+		if err != nil {
+			return fmt.Errorf("error building the reference graph: %v", err)
+		}
+
+		// Highlight what prune would delete, without recording the scan in
+		// the usage history
+		types = []string{"configmaps", "secrets", "pvcs", "pods"}
+		results, err := findCandidates(detector, windows, false)
+		if err != nil {
+			return err
+		}
+		graph.MarkCandidates(results)
+
+		return utils.OutputGraph(graph, output)
+	},
+}
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/manthan-parmar-1998/k8s-pruner/pkg/resources"
+	"github.com/manthan-parmar-1998/k8s-pruner/pkg/state"
+	"github.com/manthan-parmar-1998/k8s-pruner/pkg/utils"
+)
+
+// loadHistory opens the usage history when --state-file or --unused-for is
+// set and attaches it to the detector. It returns nil if history is disabled.
+func loadHistory(detector *resources.ResourceDetector) (*state.Store, error) {
+	if stateFile == "" && unusedFor == "" {
+		return nil, nil
+	}
+
+	unusedSince, err := utils.ParseAge(unusedFor)
+	if err != nil {
+		return nil, err
+	}
+
+	gap, err := utils.ParseDuration(maxScanGap)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --max-scan-gap: %v", err)
+	}
+
+	path := stateFile
+	if path == "" {
+		path = state.DefaultPath()
+	}
+
+	store, err := state.Load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	store.MaxScanGap = gap
+
+	detector.UseHistory(store, unusedSince)
+	return store, nil
+}
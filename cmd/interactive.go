@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/manthan-parmar-1998/k8s-pruner/pkg/resources"
+	"github.com/manthan-parmar-1998/k8s-pruner/pkg/utils"
+)
+
+// interactiveHelp explains the answers selectInteractively accepts
+const interactiveHelp = `y - delete this resource
+n - keep this resource
+a - delete this and all remaining resources of this type, still asking
+    about the ones something references
+s - keep this and all remaining resources of this type
+q - keep this and all remaining resources, and stop asking
+? - print help`
+
+// selectInteractively walks through results one item at a time, showing
+// why each was found and what references it, and returns the accepted items
+func selectInteractively(results []resources.ResourceList, refs resources.ReferenceIndex, in io.Reader, out io.Writer) ([]resources.ResourceList, error) {
+	reader := bufio.NewReader(in)
+
+	total := 0
+	for _, resourceList := range results {
+		total += len(resourceList.Items)
+	}
+
+	var selected []resources.ResourceList
+	n := 0
+	for _, resourceList := range results {
+		accepted := resources.ResourceList{ResourceType: resourceList.ResourceType, Items: []resources.ResourceItem{}}
+
+		// all and skip apply to the rest of the type
+		all, skip := false, false
+		for _, item := range resourceList.Items {
+			n++
+			references, _ := refs.ReferencesTo(resourceList.ResourceType, item)
+			if all && len(references) == 0 {
+				accepted.Items = append(accepted.Items, item)
+				continue
+			}
+			if skip {
+				continue
+			}
+
+			describeCandidate(out, n, total, resourceList.ResourceType, item, refs)
+
+		ask:
+			for {
+				fmt.Fprint(out, "Delete? [y,n,a,s,q,?]: ")
+				answer, err := reader.ReadString('\n')
+				if err != nil && (err != io.EOF || answer == "") {
+					return nil, fmt.Errorf("error reading input: %v", err)
+				}
+
+				switch strings.ToLower(strings.TrimSpace(answer)) {
+				case "y", "yes":
+					accepted.Items = append(accepted.Items, item)
+				case "n", "no":
+				case "a":
+					accepted.Items = append(accepted.Items, item)
+					all = true
+				case "s":
+					skip = true
+				case "q":
+					if len(accepted.Items) > 0 {
+						selected = append(selected, accepted)
+					}
+					return selected, nil
+				default:
+					fmt.Fprintln(out, interactiveHelp)
+					continue ask
+				}
+				break
+			}
+		}
+
+		if len(accepted.Items) > 0 {
+			selected = append(selected, accepted)
+		}
+	}
+
+	return selected, nil
+}
+
+// describeCandidate prints one item for the user to decide on
+func describeCandidate(out io.Writer, n, total int, resourceType string, item resources.ResourceItem, refs resources.ReferenceIndex) {
+	name := item.Name
+	if item.Namespace != "" {
+		name = item.Namespace + "/" + name
+	}
+	fmt.Fprintf(out, "\n(%d/%d) %s %s (age: %s)\n", n, total, resourceType, name, utils.FormatAge(time.Since(item.CreationTimestamp)))
+
+	if item.Reason != "" {
+		fmt.Fprintf(out, "  reason: %s\n", item.Reason)
+	}
+
+	references, tracked := refs.ReferencesTo(resourceType, item)
+	if !tracked {
+		return
+	}
+	if len(references) == 0 {
+		fmt.Fprintln(out, "  referenced by: nothing")
+		return
+	}
+	fmt.Fprintln(out, "  WARNING: still referenced; deleting it can break what references it")
+	fmt.Fprintln(out, "  referenced by:")
+	for _, ref := range references {
+		line := fmt.Sprintf("    %s (%s", ref.From, ref.Via)
+		if ref.Optional {
+			line += ", optional"
+		}
+		fmt.Fprintln(out, line+")")
+	}
+}
@@ -1,9 +1,13 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"strings"
 
-	"github.com/manthan-parmar-1998/k8s-pruner/pkg/client"
+	"github.com/manthan-parmar-1998/k8s-pruner/pkg/plan"
 	"github.com/manthan-parmar-1998/k8s-pruner/pkg/resources"
 	"github.com/manthan-parmar-1998/k8s-pruner/pkg/utils"
 	"github.com/spf13/cobra"
@@ -15,34 +19,69 @@ var listCmd = &cobra.Command{
 	Long: `List unused resources in a Kubernetes cluster.
 This command identifies resources that are not being used and can be safely removed.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		// Initialize Kubernetes client
-		k8sClient, err := client.NewClient(kubeconfig, context)
+		// Create resource detector
+		detector, err := newDetector()
 		if err != nil {
-			return fmt.Errorf("error creating Kubernetes client: %v", err)
+			return err
 		}
 
-		// Parse age filter if provided
-		ageFilter, err := utils.ParseAge(age)
+		// Parse the creation time window for each type
+		windows, err := utils.ParseTimeWindows(age, newerThan, before)
 		if err != nil {
 			return err
 		}
 
-		// Create resource detector
-		detector := resources.NewResourceDetector(k8sClient)
+		// Load usage history if enabled
+		history, err := loadHistory(detector)
+		if err != nil {
+			return err
+		}
 
 		// Find unused resources
-		results, err := detector.FindAllUnusedResources(namespace, ageFilter, types, labels)
+		results, err := detector.FindAllUnusedResources(namespace, windows, types, labels)
 		if err != nil {
 			return fmt.Errorf("error finding unused resources: %v", err)
 		}
 
+		// Persist what this scan saw in use
+		if history != nil {
+			if err := history.Save(); err != nil {
+				return err
+			}
+		}
+
+		// A plan records exactly what prune --plan will delete
+		if strings.ToLower(output) == "plan" {
+			return writePlan(detector, results, os.Stdout)
+		}
+
 		// Output results
 		return utils.OutputResults(results, output, "Found the following unused resources:")
 	},
 }
 
 func init() {
-	listCmd.Flags().StringSliceVar(&types, "types", []string{"configmaps", "secrets", "pvcs", "pods", "jobs", "namespaces"},
-		"Resource types to check (configmaps, secrets, pvcs, pods, jobs, namespaces)")
+	listCmd.Flags().StringSliceVar(&types, "types", nil,
+		"Resource types to check (configmaps/cm, secrets, pvcs/pvc, pods/po, jobs, namespaces/ns, or any registered detector; default all)")
 	listCmd.Flags().StringVar(&labels, "labels", "", "Label selector to filter resources")
 }
+
+// writePlan writes a plan to delete results on the current cluster
+func writePlan(detector *resources.ResourceDetector, results []resources.ResourceList, w io.Writer) error {
+	cluster, err := detector.ClusterID()
+	if err != nil {
+		return err
+	}
+
+	p, err := plan.New(results, cluster)
+	if err != nil {
+		return err
+	}
+
+	jsonData, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling to JSON: %v", err)
+	}
+	_, err = fmt.Fprintln(w, string(jsonData))
+	return err
+}
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/manthan-parmar-1998/k8s-pruner/pkg/resources"
+	"github.com/manthan-parmar-1998/k8s-pruner/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+var markCmd = &cobra.Command{
+	Use:   "mark",
+	Short: "Mark unused Kubernetes resources for a later sweep",
+	Long: `Annotate unused resources with the time they were first found unused
+(` + resources.CandidateSinceAnnotation + `), and clear the mark from resources
+that are in use again. prune --sweep later deletes only resources that have
+stayed marked for the grace period, giving owners time to notice.
+
+Marks are only cleared from resources seen in use: ones left out by --age,
+--where or --unused-for while still unused keep theirs.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dryRunMode, err := parseDryRun()
+		if err != nil {
+			return err
+		}
+
+		// Create resource detector
+		detector, err := newDetector()
+		if err != nil {
+			return err
+		}
+
+		// Parse the creation time window for each type
+		windows, err := utils.ParseTimeWindows(age, newerThan, before)
+		if err != nil {
+			return err
+		}
+
+		// Load usage history if enabled
+		history, err := loadHistory(detector)
+		if err != nil {
+			return err
+		}
+
+		// Find unused resources
+		results, err := detector.FindAllUnusedResources(namespace, windows, types, labels)
+		if err != nil {
+			return fmt.Errorf("error finding unused resources: %v", err)
+		}
+
+		// Persist what this scan saw in use
+		if history != nil {
+			if err := history.Save(); err != nil {
+				return err
+			}
+		}
+
+		// Mark new candidates and clear marks from resources in use again
+		report, err := detector.MarkCandidates(results, namespace, types, labels, time.Now(), resources.MarkOptions{
+			Label:        markLabel,
+			DryRun:       dryRunMode == dryRunClient,
+			ServerDryRun: dryRunMode == dryRunServer,
+		})
+		if err != nil {
+			return fmt.Errorf("error marking unused resources: %v", err)
+		}
+		if err := utils.OutputMarkReport(report, output, dryRunMode != dryRunNone); err != nil {
+			return err
+		}
+
+		if failed := report.Failed(); failed > 0 {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("failed to update %d of %d resources", failed, len(report.Results))
+		}
+		return nil
+	},
+}
+
+func init() {
+	markCmd.Flags().StringSliceVar(&types, "types", nil,
+		"Resource types to mark (configmaps/cm, secrets, pvcs/pvc, pods/po, jobs, namespaces/ns, or any registered detector; default all)")
+	markCmd.Flags().StringVar(&labels, "labels", "", "Label selector to filter resources")
+	markCmd.Flags().BoolVar(&markLabel, "label", false, "Also label marked resources with "+resources.CandidateLabel+"=true")
+}
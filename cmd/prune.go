@@ -5,11 +5,13 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
-	"github.com/manthan-parmar-1998/k8s-pruner/pkg/client"
+	"github.com/manthan-parmar-1998/k8s-pruner/pkg/plan"
 	"github.com/manthan-parmar-1998/k8s-pruner/pkg/resources"
 	"github.com/manthan-parmar-1998/k8s-pruner/pkg/utils"
 	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 var pruneCmd = &cobra.Command{
@@ -18,25 +20,73 @@ var pruneCmd = &cobra.Command{
 	Long: `Prune (delete) unused resources in a Kubernetes cluster.
 This command removes resources that are not being used to free up cluster resources.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		// Initialize Kubernetes client
-		k8sClient, err := client.NewClient(kubeconfig, context)
+		// Parse deletion options before doing any work
+		dryRunMode, err := parseDryRun()
 		if err != nil {
-			return fmt.Errorf("error creating Kubernetes client: %v", err)
+			return err
 		}
-
-		// Parse age filter if provided
-		ageFilter, err := utils.ParseAge(age)
+		pruneOptions, err := parsePruneOptions()
 		if err != nil {
 			return err
 		}
+		pruneOptions.DryRun = dryRunMode == dryRunServer
+		if maxDeletions < 0 || maxDeletionsPercent < 0 || maxDeletionsPercent > 100 {
+			return fmt.Errorf("--max-deletions can't be negative and --max-deletions-percent must be between 0 and 100")
+		}
+		if err := checkBackupFlags(); err != nil {
+			return err
+		}
+		if interactive && edit {
+			return fmt.Errorf("--interactive and --edit can't be combined")
+		}
+		if (interactive || edit) && force {
+			return fmt.Errorf("--interactive and --edit can't be combined with --force")
+		}
+		if savePlan != "" && !interactive && !edit {
+			return fmt.Errorf("--save-plan needs --interactive or --edit; use list -o plan to save every candidate")
+		}
+		if grace != "" && !sweep {
+			return fmt.Errorf("--grace needs --sweep")
+		}
+		if planFile != "" && (sweep || cmd.Flags().Changed("types") || cmd.Flags().Changed("labels")) {
+			return fmt.Errorf("--plan deletes exactly the planned resources and can't be combined with --types, --labels or --sweep")
+		}
+
+		// Exec plugins on PATH are only registered when asked for by type,
+		// so a plan asks for the types of its items
+		var prunePlan *plan.Plan
+		if planFile != "" {
+			prunePlan, err = plan.Load(planFile)
+			if err != nil {
+				return err
+			}
+			types = prunePlan.ResourceTypes()
+		}
 
 		// Create resource detector
-		detector := resources.NewResourceDetector(k8sClient)
+		detector, err := newDetector()
+		if err != nil {
+			return err
+		}
 
-		// Find unused resources
-		results, err := detector.FindAllUnusedResources(namespace, ageFilter, types, labels)
+		// Parse the creation time window for each type
+		windows, err := utils.ParseTimeWindows(age, newerThan, before)
 		if err != nil {
-			return fmt.Errorf("error finding unused resources: %v", err)
+			return err
+		}
+
+		// Either delete a reviewed plan or find unused resources now
+		var results []resources.ResourceList
+		var skipped []resources.DeletionResult
+		header := "Found the following unused resources:"
+		if planFile != "" {
+			results, skipped, err = loadPlan(detector, prunePlan)
+			header = "The plan deletes the following resources:"
+		} else {
+			results, err = findCandidates(detector, windows, true)
+		}
+		if err != nil {
+			return err
 		}
 
 		// Count total resources
@@ -45,52 +95,260 @@ This command removes resources that are not being used to free up cluster resour
 			totalCount += len(resourceList.Items)
 		}
 
-		if totalCount == 0 {
+		if totalCount == 0 && len(skipped) == 0 {
 			fmt.Println("No unused resources found.")
 			return nil
 		}
 
 		// Output what we found
-		if err := utils.OutputResults(results, output, "Found the following unused resources:"); err != nil {
+		if err := utils.OutputResults(results, output, header); err != nil {
 			return err
 		}
 
-		// If dry run, exit here
-		if dryRun {
-			fmt.Println("\nDRY RUN: No resources were pruned.")
-			return nil
-		}
+		// Let the user pick the resources to delete one by one, or in an editor
+		if interactive || edit {
+			if interactive {
+				refs, err := detector.References(namespace)
+				if err != nil {
+					return fmt.Errorf("error finding references: %v", err)
+				}
+				results, err = selectInteractively(results, refs, os.Stdin, os.Stdout)
+				if err != nil {
+					return err
+				}
+			} else {
+				results, err = editCandidates(results)
+				if err != nil {
+					cmd.SilenceUsage = true
+					return err
+				}
+			}
 
-		// Confirm deletion unless force flag is set
-		if !force {
-			fmt.Printf("\nAre you sure you want to delete these %d resources? (y/N): ", totalCount)
-			reader := bufio.NewReader(os.Stdin)
-			response, err := reader.ReadString('\n')
-			if err != nil {
-				return fmt.Errorf("error reading input: %v", err)
+			totalCount = 0
+			for _, resourceList := range results {
+				totalCount += len(resourceList.Items)
+			}
+
+			// Keep the selection for a later prune --plan
+			if savePlan != "" {
+				if err := savePlanFile(detector, results, savePlan); err != nil {
+					return err
+				}
+				fmt.Printf("\nSaved the selection to %s\n", savePlan)
 			}
 
-			response = strings.ToLower(strings.TrimSpace(response))
-			if response != "y" && response != "yes" {
-				fmt.Println("Operation cancelled.")
+			if totalCount == 0 {
+				fmt.Println("\nNo resources selected.")
 				return nil
 			}
 		}
 
-		// Delete resources
-		deleted, err := detector.DeleteUnusedResources(results)
+		// Check the limits, confirm, re-verify, back up and delete, carrying
+		// on past failures
+		report, err := deleteCandidates(detector, results, deleteSteps{
+			windows:    windows,
+			options:    pruneOptions,
+			verify:     planFile == "",
+			backupPath: backupPath,
+			confirm: func() (bool, error) {
+				return confirmPrune(dryRunMode, totalCount)
+			},
+			warn: os.Stderr,
+		})
 		if err != nil {
-			return fmt.Errorf("error deleting resources: %v", err)
+			cmd.SilenceUsage = true
+			return err
+		}
+		if report == nil {
+			return nil
 		}
+		report.Results = append(skipped, report.Results...)
 
-		fmt.Printf("Successfully pruned %d resources.\n", deleted)
+		if err := utils.OutputDeletionReport(*report, output); err != nil {
+			return err
+		}
+
+		// Fail the command if anything is left behind
+		if failed := report.Failed(); failed > 0 {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("failed to prune %d of %d resources", failed, len(report.Results))
+		}
 		return nil
 	},
 }
 
 func init() {
-	pruneCmd.Flags().StringSliceVar(&types, "types", []string{"configmaps", "secrets", "pvcs", "pods", "jobs", "namespaces"},
-		"Resource types to prune (configmaps, secrets, pvcs, pods, jobs, namespaces)")
+	pruneCmd.Flags().StringSliceVar(&types, "types", nil,
+		"Resource types to prune (configmaps/cm, secrets, pvcs/pvc, pods/po, jobs, namespaces/ns, or any registered detector; default all)")
 	pruneCmd.Flags().StringVar(&labels, "labels", "", "Label selector to filter resources")
 	pruneCmd.Flags().BoolVar(&force, "force", false, "Skip confirmation prompt before deleting resources")
+	pruneCmd.Flags().IntVar(&concurrency, "concurrency", 1, "Number of deletes to run in parallel")
+	pruneCmd.Flags().Float64Var(&maxDeletesPerSecond, "max-deletes-per-second", 0, "Maximum number of deletes per second (0 means unlimited)")
+	pruneCmd.Flags().StringVar(&cascade, "cascade", "background", "How to delete dependents: background, foreground or orphan")
+	pruneCmd.Flags().StringVar(&backupPath, "backup", "", "Directory, or tarball if it ends in .tar.gz, .tgz or .tar, to save the manifest of every resource to before deleting")
+	pruneCmd.Flags().StringArrayVar(&backupRecipients, "backup-recipient", nil, "Encrypt the backup with age to this public key (age1...) or the keys in this file; may be repeated")
+	pruneCmd.Flags().BoolVar(&redactSecretData, "redact-secret-data", false, "Leave the data of Secrets out of the backup, keeping only their metadata")
+	pruneCmd.Flags().IntVar(&maxDeletions, "max-deletions", 0, "Refuse to prune if more than this many resources would be deleted (0 means no limit)")
+	pruneCmd.Flags().Float64Var(&maxDeletionsPercent, "max-deletions-percent", 0, "Refuse to prune if more than this percentage of the existing resources of a type, or in a namespace, would be deleted (0 means no limit)")
+	pruneCmd.Flags().BoolVar(&iKnowWhatImDoing, "i-know-what-im-doing", false, "Prune even if --max-deletions or --max-deletions-percent is exceeded")
+	pruneCmd.Flags().BoolVar(&interactive, "interactive", false, "Confirm each resource individually, showing why it was found and what references it")
+	pruneCmd.Flags().BoolVar(&edit, "edit", false, "Open the candidates in $EDITOR and delete only the lines left when it exits")
+	pruneCmd.Flags().StringVar(&savePlan, "save-plan", "", "With --interactive or --edit, also save the selected resources as a plan for prune --plan")
+	pruneCmd.Flags().StringVar(&planFile, "plan", "", "Delete exactly the resources in a plan made with list -o plan, instead of detecting them")
+	pruneCmd.Flags().BoolVar(&sweep, "sweep", false, "Only delete resources mark has flagged as unused, and that are still unused")
+	pruneCmd.Flags().StringVar(&grace, "grace", "", "With --sweep, how long resources must have been marked (e.g., 7d; default any mark)")
+	pruneCmd.Flags().Int64Var(&gracePeriod, "grace-period", -1, "Seconds objects get to terminate gracefully (-1 uses each object's default)")
+}
+
+// Modes of --dry-run
+const (
+	dryRunNone   = "none"
+	dryRunClient = "client"
+	dryRunServer = "server"
+)
+
+// parseDryRun returns the --dry-run mode. A bare --dry-run means client, and
+// true/false are still accepted from when it was a boolean flag.
+func parseDryRun() (string, error) {
+	switch strings.ToLower(dryRun) {
+	case "", dryRunNone, "false":
+		return dryRunNone, nil
+	case dryRunClient, "true":
+		return dryRunClient, nil
+	case dryRunServer:
+		return dryRunServer, nil
+	default:
+		return "", fmt.Errorf("invalid --dry-run %q (use none, client or server)", dryRun)
+	}
+}
+
+// findCandidates finds the unused resources to prune, applying the usage
+// history and --sweep. saveHistory records what the scan saw in use; it is
+// off for read-only views like explain and graph, which shouldn't move the
+// --unused-for clock.
+func findCandidates(detector *resources.ResourceDetector, windows resources.TimeWindows, saveHistory bool) ([]resources.ResourceList, error) {
+	// Load usage history if enabled
+	history, err := loadHistory(detector)
+	if err != nil {
+		return nil, err
+	}
+
+	// Find unused resources
+	results, err := detector.FindAllUnusedResources(namespace, windows, types, labels)
+	if err != nil {
+		return nil, fmt.Errorf("error finding unused resources: %v", err)
+	}
+
+	// Persist what this scan saw in use
+	if history != nil && saveHistory {
+		if err := history.Save(); err != nil {
+			return nil, err
+		}
+	}
+
+	// A sweep only deletes what has been marked for the grace period
+	if sweep {
+		graceCutoff, err := parseGrace()
+		if err != nil {
+			return nil, err
+		}
+		results = resources.MarkedBefore(results, graceCutoff)
+	}
+
+	return results, nil
+}
+
+// confirmPrune asks before deleting count resources, unless --force,
+// --interactive, --edit or a server dry run make that unnecessary. A client
+// dry run stops here.
+func confirmPrune(dryRunMode string, count int) (bool, error) {
+	if dryRunMode == dryRunClient {
+		fmt.Println("\nDRY RUN: No resources were pruned.")
+		return false, nil
+	}
+	if force || interactive || edit || dryRunMode == dryRunServer {
+		return true, nil
+	}
+
+	fmt.Printf("\nAre you sure you want to delete these %d resources? (y/N): ", count)
+	reader := bufio.NewReader(os.Stdin)
+	response, err := reader.ReadString('\n')
+	if err != nil {
+		return false, fmt.Errorf("error reading input: %v", err)
+	}
+
+	response = strings.ToLower(strings.TrimSpace(response))
+	if response != "y" && response != "yes" {
+		fmt.Println("Operation cancelled.")
+		return false, nil
+	}
+	return true, nil
+}
+
+// loadPlan checks that p, the --plan, was made for the current cluster and
+// returns its items
+func loadPlan(detector *resources.ResourceDetector, p *plan.Plan) ([]resources.ResourceList, []resources.DeletionResult, error) {
+	cluster, err := detector.ClusterID()
+	if err != nil {
+		return nil, nil, err
+	}
+	if p.Cluster != cluster {
+		return nil, nil, fmt.Errorf("plan %s was made for cluster %s, not the current cluster %s", planFile, p.Cluster, cluster)
+	}
+
+	results, skipped := p.ResourceLists()
+	return results, skipped, nil
+}
+
+// savePlanFile writes a plan to delete results to path
+func savePlanFile(detector *resources.ResourceDetector, results []resources.ResourceList, path string) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("error saving plan: %v", err)
+	}
+	if err := writePlan(detector, results, file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// parseGrace returns the latest time a resource can have been marked at to be
+// swept
+func parseGrace() (time.Time, error) {
+	if grace == "" {
+		return time.Now(), nil
+	}
+	d, err := utils.ParseDuration(grace)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid --grace: %v", err)
+	}
+	return time.Now().Add(-d), nil
+}
+
+// parsePruneOptions builds the deletion options from the prune flags
+func parsePruneOptions() (resources.PruneOptions, error) {
+	opts := resources.PruneOptions{
+		Concurrency:  concurrency,
+		MaxPerSecond: maxDeletesPerSecond,
+	}
+
+	var propagation metav1.DeletionPropagation
+	switch strings.ToLower(cascade) {
+	case "background":
+		propagation = metav1.DeletePropagationBackground
+	case "foreground":
+		propagation = metav1.DeletePropagationForeground
+	case "orphan":
+		propagation = metav1.DeletePropagationOrphan
+	default:
+		return opts, fmt.Errorf("invalid --cascade %q (use background, foreground or orphan)", cascade)
+	}
+	opts.PropagationPolicy = &propagation
+
+	if gracePeriod >= 0 {
+		opts.GracePeriodSeconds = &gracePeriod
+	}
+
+	return opts, nil
 }
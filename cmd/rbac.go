@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/manthan-parmar-1998/k8s-pruner/pkg/resources"
+	"github.com/manthan-parmar-1998/k8s-pruner/pkg/utils"
+	"github.com/spf13/cobra"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var rbacCmd = &cobra.Command{
+	Use:   "rbac",
+	Short: "Print the ClusterRole k8s-pruner needs",
+	Long: `Print a ClusterRole with the permissions k8s-pruner needs for the given types:
+finding and deleting them, backing them up, verifying and explaining them,
+marking them, and reading the references and namespaces around them. Rules
+and plugins from the config file are included. restore also needs create on
+whatever the backup holds, which is not included.`,
+	Example: `  k8s-pruner rbac | kubectl apply -f -
+  k8s-pruner rbac --types configmaps,secrets --name pruner-config`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if _, err := loadConfig(); err != nil {
+			return err
+		}
+
+		rules, err := resources.RequiredPermissions(types)
+		if err != nil {
+			return fmt.Errorf("error collecting permissions: %v", err)
+		}
+
+		role := &rbacv1.ClusterRole{
+			TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "ClusterRole"},
+			ObjectMeta: metav1.ObjectMeta{Name: roleName},
+			Rules:      rules,
+		}
+		return utils.OutputClusterRole(role, output)
+	},
+}
+
+func init() {
+	rbacCmd.Flags().StringSliceVar(&types, "types", nil,
+		"Resource types to grant access to (configmaps/cm, secrets, pvcs/pvc, pods/po, jobs, namespaces/ns, or any registered detector; default all)")
+	rbacCmd.Flags().StringVar(&roleName, "name", "k8s-pruner", "Name of the ClusterRole")
+}
@@ -0,0 +1,247 @@
+package cmd
+
+import (
+	stdcontext "context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/manthan-parmar-1998/k8s-pruner/pkg/backup"
+	"github.com/manthan-parmar-1998/k8s-pruner/pkg/client"
+	"github.com/manthan-parmar-1998/k8s-pruner/pkg/resources"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+)
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore",
+	Short: "Re-create resources from a prune backup",
+	Long: `Re-create resources from a backup written by prune --backup.
+Objects can be selected by type, namespace and name. Namespaces are restored
+before the objects in them.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if restoreFrom == "" {
+			return fmt.Errorf("--from is required")
+		}
+		dryRunMode, err := parseDryRun()
+		if err != nil {
+			return err
+		}
+
+		ids, err := backup.ParseIdentities(identities)
+		if err != nil {
+			return err
+		}
+		b, err := backup.Open(restoreFrom, ids)
+		if err != nil {
+			return err
+		}
+
+		// Pick the entries to restore, namespaces first
+		entries := selectRestoreEntries(b.Index.Entries)
+		if len(entries) == 0 {
+			fmt.Println("Nothing in the backup matches.")
+			return nil
+		}
+
+		if dryRunMode == dryRunClient {
+			fmt.Println("Would restore:")
+			for _, entry := range entries {
+				fmt.Printf("  %s %s\n", entry.Kind, entryName(entry))
+			}
+			fmt.Println("\nDRY RUN: No resources were restored.")
+			return nil
+		}
+
+		dynamicClient, err := client.NewDynamicClient(kubeconfig, context)
+		if err != nil {
+			return fmt.Errorf("error creating Kubernetes client: %v", err)
+		}
+
+		createOptions := metav1.CreateOptions{}
+		if dryRunMode == dryRunServer {
+			createOptions.DryRun = []string{metav1.DryRunAll}
+		}
+
+		failed := 0
+		for _, entry := range entries {
+			status, err := restoreEntry(dynamicClient, b, entry, createOptions)
+			line := fmt.Sprintf("  %-12s %s %s", status, entry.Kind, entryName(entry))
+			if err != nil {
+				failed++
+				line += ": " + err.Error()
+			} else if entry.Redacted {
+				line += ": data was redacted in the backup"
+			}
+			fmt.Println(line)
+		}
+
+		if failed > 0 {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("failed to restore %d of %d resources", failed, len(entries))
+		}
+		return nil
+	},
+}
+
+func init() {
+	restoreCmd.Flags().StringVar(&restoreFrom, "from", "", "Backup directory or tarball written by prune --backup")
+	restoreCmd.Flags().StringSliceVar(&types, "types", nil, "Resource types to restore, e.g. configmaps/cm or a CRD's resource name (default all)")
+	restoreCmd.Flags().StringArrayVar(&identities, "identity", nil, "File with the age private key to decrypt an encrypted backup; may be repeated")
+	restoreCmd.Flags().StringSliceVar(&restoreNames, "names", nil, "Names of the objects to restore (default all)")
+}
+
+// selectRestoreEntries returns the backup entries matching --types,
+// --namespace and --names, plus the backed up namespaces they live in,
+// with Namespaces ordered first
+func selectRestoreEntries(entries []backup.Entry) []backup.Entry {
+	namespaces := make(map[string]backup.Entry)
+	for _, entry := range entries {
+		if isNamespaceEntry(entry) {
+			namespaces[entry.Name] = entry
+		}
+	}
+
+	selected := make(map[string]backup.Entry)
+	for _, entry := range entries {
+		if !matchesRestoreFilters(entry) {
+			continue
+		}
+		selected[entry.File] = entry
+
+		// An object can only come back if its namespace does
+		if ns, ok := namespaces[entry.Namespace]; ok {
+			selected[ns.File] = ns
+		}
+	}
+
+	result := make([]backup.Entry, 0, len(selected))
+	for _, entry := range selected {
+		result = append(result, entry)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if isNamespaceEntry(result[i]) != isNamespaceEntry(result[j]) {
+			return isNamespaceEntry(result[i])
+		}
+		return result[i].File < result[j].File
+	})
+	return result
+}
+
+// matchesRestoreFilters reports whether entry was selected on the command line
+func matchesRestoreFilters(entry backup.Entry) bool {
+	if namespace != "" {
+		// -n selects the namespace itself as well as what's in it
+		inNamespace := entry.Namespace == namespace || (isNamespaceEntry(entry) && entry.Name == namespace)
+		if !inNamespace {
+			return false
+		}
+	}
+
+	if len(restoreNames) > 0 && !containsString(restoreNames, entry.Name) {
+		return false
+	}
+
+	if len(types) == 0 {
+		return true
+	}
+	for _, t := range types {
+		t = strings.ToLower(t)
+		if t == entry.Resource || t == strings.ToLower(entry.Kind) {
+			return true
+		}
+		if kind, err := resources.LookupKind(t); err == nil &&
+			kind.Resource.Group == entry.Group && kind.Resource.Resource == entry.Resource {
+			return true
+		}
+	}
+	return false
+}
+
+// restoreEntry creates one backed up object, creating its namespace first
+// if the cluster doesn't have it. It returns a short status for the report.
+func restoreEntry(client dynamic.Interface, b *backup.Backup, entry backup.Entry, opts metav1.CreateOptions) (string, error) {
+	obj, err := b.Object(entry)
+	if err != nil {
+		return "error", err
+	}
+
+	ctx := stdcontext.Background()
+	if entry.Namespace != "" {
+		created, err := ensureNamespace(ctx, client, entry.Namespace, opts)
+		if err != nil {
+			return "error", err
+		}
+		// A dry run can't create objects in a namespace it didn't create
+		if created && len(opts.DryRun) > 0 {
+			return "would create", nil
+		}
+	}
+
+	_, err = client.Resource(entry.GroupVersionResource()).Namespace(entry.Namespace).Create(ctx, obj, opts)
+	switch {
+	case apierrors.IsAlreadyExists(err):
+		return "exists", nil
+	case err != nil:
+		return "error", err
+	case len(opts.DryRun) > 0:
+		return "would create", nil
+	default:
+		return "created", nil
+	}
+}
+
+// ensureNamespace creates an empty namespace if it doesn't exist, for
+// objects whose Namespace isn't in the backup. It reports whether it did.
+func ensureNamespace(ctx stdcontext.Context, client dynamic.Interface, name string, opts metav1.CreateOptions) (bool, error) {
+	namespaces := client.Resource(corev1.SchemeGroupVersion.WithResource("namespaces"))
+
+	_, err := namespaces.Get(ctx, name, metav1.GetOptions{})
+	if !apierrors.IsNotFound(err) {
+		return false, err
+	}
+
+	ns := &unstructured.Unstructured{}
+	ns.SetAPIVersion("v1")
+	ns.SetKind("Namespace")
+	ns.SetName(name)
+	_, err = namespaces.Create(ctx, ns, opts)
+	if apierrors.IsAlreadyExists(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error creating namespace %s: %v", name, err)
+	}
+	if len(opts.DryRun) == 0 {
+		fmt.Fprintf(os.Stderr, "Created namespace %s\n", name)
+	}
+	return true, nil
+}
+
+// isNamespaceEntry reports whether entry is a Namespace
+func isNamespaceEntry(entry backup.Entry) bool {
+	return entry.Group == "" && entry.Resource == "namespaces"
+}
+
+// entryName formats an entry as namespace/name, or name if cluster-scoped
+func entryName(entry backup.Entry) string {
+	if entry.Namespace == "" {
+		return entry.Name
+	}
+	return entry.Namespace + "/" + entry.Name
+}
+
+// containsString reports whether values contains s
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -6,14 +6,55 @@ import (
 
 var (
 	namespace  string
-	dryRun     bool
+	dryRun     string
 	age        string
+	newerThan  string
+	before     string
 	context    string
 	kubeconfig string
 	output     string
 	force      bool
 	types      []string
 	labels     string
+	stateFile  string
+	unusedFor  string
+	maxScanGap string
+	configFile string
+	where      []string
+
+	pluginTimeout string
+
+	// prune only
+	concurrency         int
+	maxDeletesPerSecond float64
+	cascade             string
+	gracePeriod         int64
+	backupPath          string
+	backupRecipients    []string
+	redactSecretData    bool
+	sweep               bool
+	planFile            string
+	maxDeletions        int
+	maxDeletionsPercent float64
+	iKnowWhatImDoing    bool
+	interactive         bool
+	edit                bool
+	savePlan            string
+	grace               string
+
+	// mark only
+	markLabel bool
+
+	// dangling only
+	includeOptional bool
+
+	// rbac only
+	roleName string
+
+	// restore only
+	restoreFrom  string
+	restoreNames []string
+	identities   []string
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -22,6 +63,8 @@ var rootCmd = &cobra.Command{
 	Short: "A tool to list and prune unused Kubernetes resources",
 	Long: `k8s-pruner helps clean up resources in a Kubernetes cluster to save costs and improve performance.
 It can identify and remove unused ConfigMaps, Secrets, PVCs, completed Jobs, and more.`,
+	// main prints the error
+	SilenceErrors: true,
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -31,14 +74,31 @@ func Execute() error {
 
 func init() {
 	rootCmd.PersistentFlags().StringVarP(&namespace, "namespace", "n", "", "Namespace to target (default is all namespaces)")
-	rootCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "Print resources that would be pruned without actually deleting them")
-	rootCmd.PersistentFlags().StringVar(&age, "age", "", "Only consider resources older than this value (e.g., 24h, 7d)")
+	rootCmd.PersistentFlags().StringVar(&dryRun, "dry-run", "none", "Don't delete anything: \"client\" only prints the resources that would be pruned, \"server\" also sends each delete as a server-side dry run")
+	rootCmd.PersistentFlags().Lookup("dry-run").NoOptDefVal = "client"
+	rootCmd.PersistentFlags().StringVar(&age, "age", "", "Only consider resources older than this value (e.g., 24h, 7d, 1d12h, 2w or a timestamp), optionally per type (e.g., 7d,pods=1h,jobs=1d,configmaps=30d)")
+	rootCmd.PersistentFlags().StringVar(&age, "older-than", "", "Alias for --age")
+	rootCmd.PersistentFlags().StringVar(&newerThan, "newer-than", "", "Only consider resources newer than this value (e.g., 30d)")
+	rootCmd.PersistentFlags().StringVar(&before, "before", "", "Only consider resources created before this time (e.g., 2026-01-01T00:00:00Z)")
 	rootCmd.PersistentFlags().StringVar(&context, "context", "", "The name of the kubeconfig context to use")
 	rootCmd.PersistentFlags().StringVar(&kubeconfig, "kubeconfig", "", "Path to the kubeconfig file to use")
-	rootCmd.PersistentFlags().StringVarP(&output, "output", "o", "text", "Output format (text, json, yaml)")
+	rootCmd.PersistentFlags().StringVarP(&output, "output", "o", "text", "Output format (text, json, yaml; list also supports plan, graph supports dot and mermaid)")
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", "", "Path to the k8s-pruner config file (default ~/.k8s-pruner/config.yaml)")
+	rootCmd.PersistentFlags().StringVar(&pluginTimeout, "plugin-timeout", "5m", "Longest time an exec plugin may run before it is stopped; 0 means no limit")
+	rootCmd.PersistentFlags().StringArrayVar(&where, "where", nil, "CEL expression candidates must match, optionally for one type (e.g., 'secrets=object.type == \"Opaque\" && size > 100000'); may be repeated")
+	rootCmd.PersistentFlags().StringVar(&stateFile, "state-file", "", "File that records when ConfigMaps, Secrets and PVCs were last seen in use (default ~/.k8s-pruner/state.json when --unused-for is set)")
+	rootCmd.PersistentFlags().StringVar(&unusedFor, "unused-for", "", "Only consider ConfigMaps, Secrets, PVCs and exec plugin resources unreferenced in every recorded scan over this period (e.g., 30d)")
+	rootCmd.PersistentFlags().StringVar(&maxScanGap, "max-scan-gap", "25h", "Longest time between two recorded scans that --unused-for still treats as continuous history; a longer gap restarts it")
 
 	// Add subcommands
+	rootCmd.AddCommand(danglingCmd)
+	rootCmd.AddCommand(explainCmd)
+	rootCmd.AddCommand(graphCmd)
 	rootCmd.AddCommand(listCmd)
+	rootCmd.AddCommand(markCmd)
 	rootCmd.AddCommand(pruneCmd)
+	rootCmd.AddCommand(rbacCmd)
+	rootCmd.AddCommand(restoreCmd)
+	rootCmd.AddCommand(tuiCmd)
 	rootCmd.AddCommand(versionCmd)
 }
@@ -0,0 +1,206 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/manthan-parmar-1998/k8s-pruner/pkg/client"
+	"github.com/manthan-parmar-1998/k8s-pruner/pkg/config"
+	"github.com/manthan-parmar-1998/k8s-pruner/pkg/plugin"
+	"github.com/manthan-parmar-1998/k8s-pruner/pkg/resources"
+	"github.com/manthan-parmar-1998/k8s-pruner/pkg/utils"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// newDetector creates the Kubernetes clients and a resource detector, and
+// registers the detectors defined outside this binary
+func newDetector() (*resources.ResourceDetector, error) {
+	// Initialize Kubernetes clients
+	k8sClient, err := client.NewClient(kubeconfig, context)
+	if err != nil {
+		return nil, fmt.Errorf("error creating Kubernetes client: %v", err)
+	}
+	dynamicClient, err := client.NewDynamicClient(kubeconfig, context)
+	if err != nil {
+		return nil, fmt.Errorf("error creating Kubernetes client: %v", err)
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	// Compile --where and policy expressions up front so mistakes fail fast
+	filters, err := utils.ParseWhere(where)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range cfg.Policies {
+		policyTypes := p.Types
+		if len(policyTypes) == 0 {
+			policyTypes = []string{""}
+		}
+		for _, resourceType := range policyTypes {
+			if err := utils.AddFilter(&filters, resourceType, p.Expression); err != nil {
+				return nil, fmt.Errorf("error in config policy: %v", err)
+			}
+		}
+	}
+
+	detector := resources.NewResourceDetectorWithClients(resources.Clients{
+		Kube:    k8sClient,
+		Dynamic: dynamicClient,
+	})
+	detector.UseFilters(filters)
+	return detector, nil
+}
+
+// loadConfig loads the config file and registers the rules and plugins it
+// defines, along with the plugins on PATH that --types names
+func loadConfig() (*config.Config, error) {
+	cfg, err := config.Load(configFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := registerRules(cfg); err != nil {
+		return nil, err
+	}
+	if err := registerPlugins(cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// registerRules registers a detector for each custom resource rule in the config file
+func registerRules(cfg *config.Config) error {
+	for _, rc := range cfg.Rules {
+		rule := &resources.Rule{
+			RuleName:     rc.Name,
+			ResourceType: rc.ResourceType,
+			Resource:     schema.GroupVersionResource{Group: rc.Group, Version: rc.Version, Resource: rc.Resource},
+			KeepLast:     rc.KeepLast,
+			GroupBy:      rc.GroupBy,
+		}
+		if rule.ResourceType == "" {
+			rule.ResourceType = rc.Name
+		}
+
+		if rc.MinAge != "" {
+			minAge, err := utils.ParseDuration(rc.MinAge)
+			if err != nil {
+				return fmt.Errorf("rule %s: invalid minAge: %v", rc.Name, err)
+			}
+			rule.MinAge = minAge
+		}
+
+		for _, mc := range rc.Match {
+			rule.Match = append(rule.Match, resources.RuleMatch{
+				Field:     mc.Field,
+				In:        mc.In,
+				Condition: mc.Condition,
+				Status:    mc.Status,
+			})
+		}
+
+		err := resources.Register(rule, resources.Kind{
+			ResourceType: rule.ResourceType,
+			Namespaced:   !rc.ClusterScoped,
+			Resource:     rule.Resource,
+		})
+		if err != nil {
+			return fmt.Errorf("rule %s: %v", rc.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// registerPlugins registers the exec plugins listed in the config file, and
+// those found on PATH that --types names. Configured plugins take precedence.
+func registerPlugins(cfg *config.Config) error {
+	timeout, err := parsePluginTimeout(pluginTimeout)
+	if err != nil {
+		return fmt.Errorf("invalid --plugin-timeout: %v", err)
+	}
+
+	discovered := plugin.Discover(os.Getenv("PATH"))
+	for _, p := range discovered {
+		p.Timeout = timeout
+	}
+
+	byName := make(map[string]*plugin.Plugin)
+	for _, p := range discovered {
+		byName[p.Kind.Name] = p
+	}
+
+	var configured []*plugin.Plugin
+	for _, pc := range cfg.Plugins {
+		p := byName[pc.Name]
+		if pc.Path != "" {
+			p = plugin.New(pc.Name, pc.Path)
+			p.Timeout = timeout
+		}
+		if p == nil {
+			return fmt.Errorf("plugin %s: %s%s not found on PATH", pc.Name, plugin.Prefix, pc.Name)
+		}
+
+		if pc.Timeout != "" {
+			p.Timeout, err = parsePluginTimeout(pc.Timeout)
+			if err != nil {
+				return fmt.Errorf("plugin %s: invalid timeout: %v", pc.Name, err)
+			}
+		}
+
+		if pc.ResourceType != "" {
+			p.Kind.ResourceType = pc.ResourceType
+		}
+		p.Kind.Namespaced = !pc.ClusterScoped
+		if pc.Resource != "" {
+			p.Resource = &schema.GroupVersionResource{Group: pc.Group, Version: pc.Version, Resource: pc.Resource}
+			p.Kind.Resource = *p.Resource
+		}
+
+		delete(byName, pc.Name)
+		configured = append(configured, p)
+	}
+
+	kubeconfigPath := client.KubeconfigPath(kubeconfig)
+
+	for _, p := range configured {
+		p.Kubeconfig, p.Context = kubeconfigPath, context
+		if err := resources.Register(p, p.Kind); err != nil {
+			return fmt.Errorf("plugin %s: %v", p.Kind.Name, err)
+		}
+	}
+
+	// Any executable on PATH with the prefix would otherwise take part in
+	// every scan and prune, so the others run only when named
+	requested := make(map[string]bool)
+	for _, t := range types {
+		requested[strings.ToLower(strings.TrimSpace(t))] = true
+	}
+
+	// A stray executable on PATH shouldn't break the tool, so only warn
+	for _, p := range discovered {
+		if byName[p.Kind.Name] != p || !requested[strings.ToLower(p.Kind.Name)] {
+			continue
+		}
+		p.Kubeconfig, p.Context = kubeconfigPath, context
+		if err := resources.Register(p, p.Kind); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: ignoring plugin %s: %v\n", p.Path, err)
+		}
+	}
+
+	return nil
+}
+
+// parsePluginTimeout parses a plugin timeout, where "0" means no limit
+func parsePluginTimeout(value string) (time.Duration, error) {
+	if value == "0" {
+		return 0, nil
+	}
+	return utils.ParseDuration(value)
+}
@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/manthan-parmar-1998/k8s-pruner/pkg/resources"
+	"github.com/manthan-parmar-1998/k8s-pruner/pkg/tui"
+	"github.com/manthan-parmar-1998/k8s-pruner/pkg/utils"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+var tuiCmd = &cobra.Command{
+	Use:   "tui",
+	Short: "Browse and prune unused Kubernetes resources in a terminal UI",
+	Long: `Browse unused resources in a full-screen terminal UI: a tree of types,
+namespaces and objects with filtering, details and multi-select. Deleting from
+the UI works like prune, including re-verification and --dry-run.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Fail before scanning if there is no terminal to show the UI on
+		if err := tui.CheckTerminal(os.Stdin, os.Stdout); err != nil {
+			return err
+		}
+
+		dryRunMode, err := parseDryRun()
+		if err != nil {
+			return err
+		}
+		pruneOptions, err := parsePruneOptions()
+		if err != nil {
+			return err
+		}
+		pruneOptions.DryRun = dryRunMode == dryRunServer
+		if err := checkBackupFlags(); err != nil {
+			return err
+		}
+
+		// Create resource detector
+		detector, err := newDetector()
+		if err != nil {
+			return err
+		}
+
+		// Parse the creation time window for each type
+		windows, err := utils.ParseTimeWindows(age, newerThan, before)
+		if err != nil {
+			return err
+		}
+
+		// Find unused resources and what references them
+		results, err := findCandidates(detector, windows, true)
+		if err != nil {
+			return err
+		}
+		refs, err := detector.References(namespace)
+		if err != nil {
+			return fmt.Errorf("error finding references: %v", err)
+		}
+
+		backend := &tuiBackend{
+			detector:     detector,
+			windows:      windows,
+			pruneOptions: pruneOptions,
+			clientDryRun: dryRunMode == dryRunClient,
+		}
+		err = tui.Run(tui.New(results, refs, backend), os.Stdin, os.Stdout)
+		os.Stderr.Write(backend.messages.Bytes())
+		return err
+	},
+}
+
+func init() {
+	tuiCmd.Flags().StringSliceVar(&types, "types", nil,
+		"Resource types to browse (configmaps/cm, secrets, pvcs/pvc, pods/po, jobs, namespaces/ns, or any registered detector; default all)")
+	tuiCmd.Flags().StringVar(&labels, "labels", "", "Label selector to filter resources")
+	tuiCmd.Flags().StringVar(&backupPath, "backup", "", "Directory, or tarball if it ends in .tar.gz, .tgz or .tar, to save the manifest of every resource to before deleting")
+	tuiCmd.Flags().StringArrayVar(&backupRecipients, "backup-recipient", nil, "Encrypt the backup with age to this public key (age1...) or the keys in this file; may be repeated")
+	tuiCmd.Flags().BoolVar(&redactSecretData, "redact-secret-data", false, "Leave the data of Secrets out of the backup, keeping only their metadata")
+	tuiCmd.Flags().IntVar(&maxDeletions, "max-deletions", 0, "Refuse to delete if more than this many resources would be deleted at once (0 means no limit)")
+	tuiCmd.Flags().Float64Var(&maxDeletionsPercent, "max-deletions-percent", 0, "Refuse to delete if more than this percentage of the existing resources of a type, or in a namespace, would be deleted at once (0 means no limit)")
+	tuiCmd.Flags().BoolVar(&iKnowWhatImDoing, "i-know-what-im-doing", false, "Delete even if --max-deletions or --max-deletions-percent is exceeded")
+}
+
+// tuiBackend connects the TUI to the cluster through the same detector and
+// deletion path as prune
+type tuiBackend struct {
+	detector     *resources.ResourceDetector
+	windows      resources.TimeWindows
+	pruneOptions resources.PruneOptions
+	clientDryRun bool
+
+	// deletions counts the deletions that ran, each of which gets its own backup
+	deletions int
+	// messages holds the warnings and backup summaries of deletions, which
+	// can't be printed over the UI, until it exits
+	messages bytes.Buffer
+}
+
+// Manifest returns the YAML of an item, without managedFields
+func (b *tuiBackend) Manifest(resourceType string, item resources.ResourceItem) (string, error) {
+	obj, err := b.detector.Object(resourceType, item)
+	if err != nil {
+		return "", err
+	}
+	unstructured.RemoveNestedField(obj.Object, "metadata", "managedFields")
+
+	data, err := yaml.Marshal(obj.Object)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// Delete deletes the selection through the same path as prune: limits,
+// re-verification and backup included
+func (b *tuiBackend) Delete(selection []resources.ResourceList) (resources.DeletionReport, error) {
+	report, err := deleteCandidates(b.detector, selection, deleteSteps{
+		windows: b.windows,
+		options: b.pruneOptions,
+		verify:  true,
+		// The UI asked already; a client dry run stops once the limits pass
+		confirm:    func() (bool, error) { return !b.clientDryRun, nil },
+		backupPath: numberedBackupPath(backupPath, b.deletions+1),
+		warn:       &b.messages,
+	})
+	if err != nil {
+		return resources.DeletionReport{}, err
+	}
+	if report != nil {
+		b.deletions++
+		return *report, nil
+	}
+
+	// Client dry run: report what would have been deleted
+	dryRun := resources.DeletionReport{DryRun: true}
+	for _, resourceList := range selection {
+		for _, item := range resourceList.Items {
+			dryRun.Results = append(dryRun.Results, resources.DeletionResult{
+				ResourceType: resourceList.ResourceType,
+				Name:         item.Name,
+				Namespace:    item.Namespace,
+				Status:       resources.StatusDeleted,
+			})
+		}
+	}
+	return dryRun, nil
+}
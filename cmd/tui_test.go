@@ -0,0 +1,209 @@
+package cmd
+
+import (
+	stdcontext "context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/manthan-parmar-1998/k8s-pruner/pkg/backup"
+	"github.com/manthan-parmar-1998/k8s-pruner/pkg/resources"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+// setFlag sets a flag variable for the duration of a test
+func setFlag[T any](t *testing.T, flag *T, value T) {
+	t.Helper()
+	previous := *flag
+	*flag = value
+	t.Cleanup(func() { *flag = previous })
+}
+
+// newTestBackend returns a TUI backend on a fake cluster, the candidates
+// found in it and the clientset behind it. team-a has the unused ConfigMaps
+// old-config and stale-config, and app-config, which pod web mounts.
+func newTestBackend(t *testing.T) (*tuiBackend, []resources.ResourceList, *fake.Clientset) {
+	t.Helper()
+
+	created := metav1.NewTime(time.Now().Add(-48 * time.Hour))
+	configMap := func(name string) runtime.Object {
+		return &corev1.ConfigMap{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+			ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: name, CreationTimestamp: created},
+		}
+	}
+	objects := []runtime.Object{
+		configMap("app-config"),
+		configMap("old-config"),
+		configMap("stale-config"),
+		podMounting("web", "app-config"),
+	}
+
+	client := fake.NewSimpleClientset(objects...)
+	detector := resources.NewResourceDetectorWithClients(resources.Clients{
+		Kube:    client,
+		Dynamic: dynamicfake.NewSimpleDynamicClient(scheme.Scheme, objects...),
+	})
+
+	windows := resources.TimeWindows{}
+	results, err := detector.FindAllUnusedResources("", windows, []string{"configmaps"}, "")
+	if err != nil {
+		t.Fatalf("finding candidates: %v", err)
+	}
+	if len(results) != 1 || len(results[0].Items) != 2 {
+		t.Fatalf("found %v, want old-config and stale-config", results)
+	}
+
+	return &tuiBackend{detector: detector, windows: windows}, results, client
+}
+
+// podMounting returns a pod in team-a mounting the ConfigMap configMap
+func podMounting(name, configMap string) *corev1.Pod {
+	return &corev1.Pod{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+		ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: name},
+		Spec: corev1.PodSpec{Volumes: []corev1.Volume{{
+			Name: "config",
+			VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: configMap},
+			}},
+		}}},
+	}
+}
+
+// only returns the candidates named name
+func only(results []resources.ResourceList, name string) []resources.ResourceList {
+	var selection []resources.ResourceList
+	for _, resourceList := range results {
+		for _, item := range resourceList.Items {
+			if item.Name == name {
+				selection = append(selection, resources.ResourceList{ResourceType: resourceList.ResourceType, Items: []resources.ResourceItem{item}})
+			}
+		}
+	}
+	return selection
+}
+
+// exists reports whether the ConfigMap team-a/name exists
+func exists(t *testing.T, client *fake.Clientset, name string) bool {
+	t.Helper()
+	_, err := client.CoreV1().ConfigMaps("team-a").Get(stdcontext.Background(), name, metav1.GetOptions{})
+	return err == nil
+}
+
+func TestTUIDeleteChecksLimits(t *testing.T) {
+	backend, results, client := newTestBackend(t)
+	setFlag(t, &maxDeletions, 1)
+
+	if _, err := backend.Delete(results); err == nil || !strings.Contains(err.Error(), "deletion limits exceeded") {
+		t.Fatalf("Delete returned %v, want the limits refused", err)
+	}
+	for _, name := range []string{"old-config", "stale-config"} {
+		if !exists(t, client, name) {
+			t.Errorf("%s was deleted past --max-deletions", name)
+		}
+	}
+
+	// --i-know-what-im-doing deletes anyway and keeps the warning for later
+	setFlag(t, &iKnowWhatImDoing, true)
+	report, err := backend.Delete(results)
+	if err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if report.Count(resources.StatusDeleted) != 2 {
+		t.Fatalf("report %+v, want both deleted", report)
+	}
+	if !strings.Contains(backend.messages.String(), "Warning: deletion limits exceeded") {
+		t.Errorf("messages %q don't warn about the limits", backend.messages.String())
+	}
+}
+
+func TestTUIDeleteVerifies(t *testing.T) {
+	backend, results, client := newTestBackend(t)
+
+	// A pod starts using old-config while the UI is open
+	if _, err := client.CoreV1().Pods("team-a").Create(stdcontext.Background(), podMounting("worker", "old-config"), metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := backend.Delete(results)
+	if err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	for _, result := range report.Results {
+		switch result.Name {
+		case "old-config":
+			if result.Status != resources.StatusSkipped || result.Reason != "now in use" {
+				t.Errorf("old-config: %s %q, want skipped as now in use", result.Status, result.Reason)
+			}
+		case "stale-config":
+			if result.Status != resources.StatusDeleted {
+				t.Errorf("stale-config: %s, want deleted", result.Status)
+			}
+		}
+	}
+	if !exists(t, client, "old-config") {
+		t.Error("old-config was deleted while in use")
+	}
+	if exists(t, client, "stale-config") {
+		t.Error("stale-config still exists")
+	}
+}
+
+func TestTUIDeleteBacksUp(t *testing.T) {
+	backend, results, _ := newTestBackend(t)
+	path := filepath.Join(t.TempDir(), "backup")
+	setFlag(t, &backupPath, path)
+
+	// Every deletion from the same session gets its own backup
+	for _, name := range []string{"old-config", "stale-config"} {
+		report, err := backend.Delete(only(results, name))
+		if err != nil {
+			t.Fatalf("Delete %s: %v", name, err)
+		}
+		if report.Count(resources.StatusDeleted) != 1 {
+			t.Fatalf("report %+v, want %s deleted", report, name)
+		}
+	}
+
+	for path, name := range map[string]string{path: "old-config", path + "-2": "stale-config"} {
+		b, err := backup.Open(path, nil)
+		if err != nil {
+			t.Fatalf("opening %s: %v", path, err)
+		}
+		if len(b.Index.Entries) != 1 || b.Index.Entries[0].Name != name {
+			t.Errorf("%s holds %+v, want %s", path, b.Index.Entries, name)
+		}
+	}
+	if got := strings.Count(backend.messages.String(), "Backed up 1 resources to"); got != 2 {
+		t.Errorf("messages %q, want a summary of each backup", backend.messages.String())
+	}
+}
+
+func TestTUIClientDryRun(t *testing.T) {
+	backend, results, client := newTestBackend(t)
+	backend.clientDryRun = true
+	setFlag(t, &backupPath, filepath.Join(t.TempDir(), "backup"))
+
+	report, err := backend.Delete(results)
+	if err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if !report.DryRun || report.Count(resources.StatusDeleted) != 2 {
+		t.Fatalf("report %+v, want both as a dry run", report)
+	}
+	for _, name := range []string{"old-config", "stale-config"} {
+		if !exists(t, client, name) {
+			t.Errorf("%s was deleted in a client dry run", name)
+		}
+	}
+	if backend.deletions != 0 {
+		t.Errorf("a dry run counted as deletion %d", backend.deletions)
+	}
+}
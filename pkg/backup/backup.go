@@ -0,0 +1,484 @@
+package backup
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"filippo.io/age"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// APIVersion identifies the backup index format
+	APIVersion = "k8s-pruner/v1"
+	// IndexFile is the name of the index inside a backup
+	IndexFile = "index.json"
+)
+
+// Index lists the manifests in a backup
+type Index struct {
+	APIVersion string    `json:"apiVersion"`
+	Kind       string    `json:"kind"`
+	Created    time.Time `json:"created"`
+	Entries    []Entry   `json:"entries"`
+}
+
+// Entry describes one backed up object
+type Entry struct {
+	Group     string `json:"group,omitempty"`
+	Version   string `json:"version"`
+	Resource  string `json:"resource"`
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	// Redacted is set when a Secret's data was left out of the manifest
+	Redacted bool `json:"redacted,omitempty"`
+	// File is the manifest's path relative to the backup root
+	File string `json:"file"`
+}
+
+// GroupVersionResource returns the API resource the object belongs to
+func (e Entry) GroupVersionResource() schema.GroupVersionResource {
+	return schema.GroupVersionResource{Group: e.Group, Version: e.Version, Resource: e.Resource}
+}
+
+// IsTarball reports whether path names a tarball rather than a directory.
+// An encrypted tarball may carry an extra .age suffix.
+func IsTarball(path string) bool {
+	path = strings.TrimSuffix(path, ".age")
+	return strings.HasSuffix(path, ".tar.gz") || strings.HasSuffix(path, ".tgz") || strings.HasSuffix(path, ".tar")
+}
+
+// Options controls what a Writer stores and how
+type Options struct {
+	// Recipients, when set, encrypts the backup with age to these public
+	// keys. Only tarballs can be encrypted.
+	Recipients []age.Recipient
+	// RedactSecretData leaves the data of Secrets out of their manifests
+	RedactSecretData bool
+}
+
+// Writer writes manifests to a backup directory or tarball. The index is
+// written by Close, so a backup without one is incomplete.
+type Writer struct {
+	path   string
+	opts   Options
+	index  Index
+	closed bool
+	// closeErr is what Close returned the first time
+	closeErr error
+
+	// Set for tarballs only
+	file    *os.File
+	encrypt io.WriteCloser
+	gz      *gzip.Writer
+	tar     *tar.Writer
+}
+
+// Create starts a new backup at path. Paths ending in .tar.gz, .tgz or .tar,
+// optionally followed by .age, are written as a tarball, anything else as a
+// directory.
+func Create(path string, opts Options) (*Writer, error) {
+	w := &Writer{
+		path:  path,
+		opts:  opts,
+		index: Index{APIVersion: APIVersion, Kind: "BackupIndex", Created: time.Now().UTC(), Entries: []Entry{}},
+	}
+
+	if !IsTarball(path) {
+		if len(opts.Recipients) > 0 {
+			return nil, fmt.Errorf("encrypted backups must be tarballs, e.g. %s.tar.gz.age", strings.TrimSuffix(path, "/"))
+		}
+		if err := os.MkdirAll(path, 0700); err != nil {
+			return nil, fmt.Errorf("error creating backup directory: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(path, IndexFile)); err == nil {
+			return nil, fmt.Errorf("%s already contains a backup", path)
+		}
+		return w, nil
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("error creating backup: %v", err)
+	}
+	w.file = file
+
+	// Layers from the outside in: age, gzip, tar
+	var out io.Writer = file
+	if len(opts.Recipients) > 0 {
+		w.encrypt, err = age.Encrypt(file, opts.Recipients...)
+		if err != nil {
+			file.Close()
+			os.Remove(path)
+			return nil, fmt.Errorf("error encrypting backup: %v", err)
+		}
+		out = w.encrypt
+	}
+	if strings.HasSuffix(strings.TrimSuffix(path, ".age"), ".tar") {
+		w.tar = tar.NewWriter(out)
+	} else {
+		w.gz = gzip.NewWriter(out)
+		w.tar = tar.NewWriter(w.gz)
+	}
+	return w, nil
+}
+
+// Path returns where the backup is written
+func (w *Writer) Path() string {
+	return w.path
+}
+
+// Count returns the number of manifests written so far
+func (w *Writer) Count() int {
+	return len(w.index.Entries)
+}
+
+// Write adds obj, a resource of gvr, to the backup. Server-populated fields
+// are stripped so the manifest can be created again as is.
+func (w *Writer) Write(gvr schema.GroupVersionResource, obj *unstructured.Unstructured) error {
+	if w.closed {
+		return fmt.Errorf("backup %s is already closed", w.path)
+	}
+	obj = Strip(obj)
+
+	redacted := false
+	if w.opts.RedactSecretData && gvr.Group == "" && gvr.Resource == "secrets" {
+		delete(obj.Object, "data")
+		delete(obj.Object, "stringData")
+		// kubectl apply keeps a copy of the data here too
+		unstructured.RemoveNestedField(obj.Object, "metadata", "annotations", "kubectl.kubernetes.io/last-applied-configuration")
+		redacted = true
+	}
+
+	data, err := yaml.Marshal(obj.Object)
+	if err != nil {
+		return fmt.Errorf("error marshaling %s/%s: %v", obj.GetNamespace(), obj.GetName(), err)
+	}
+
+	entry := Entry{
+		Group:     gvr.Group,
+		Version:   gvr.Version,
+		Resource:  gvr.Resource,
+		Kind:      obj.GetKind(),
+		Namespace: obj.GetNamespace(),
+		Name:      obj.GetName(),
+		Redacted:  redacted,
+	}
+	entry.File = manifestFile(entry)
+
+	if err := w.writeFile(entry.File, data); err != nil {
+		return err
+	}
+	w.index.Entries = append(w.index.Entries, entry)
+	return nil
+}
+
+// Close writes the index and finishes the backup. Closing it again returns
+// the result of the first Close.
+func (w *Writer) Close() error {
+	if !w.closed {
+		w.closed = true
+		w.closeErr = w.finish()
+	}
+	return w.closeErr
+}
+
+// finish writes the index and closes every layer of a tarball. The file is
+// closed whatever fails, and a tarball that couldn't be finished is removed.
+// A directory is left without its index, which marks it as incomplete.
+func (w *Writer) finish() error {
+	err := w.writeIndex()
+	if w.tar == nil {
+		return err
+	}
+
+	if closeErr := w.tar.Close(); closeErr != nil && err == nil {
+		err = fmt.Errorf("error writing backup: %v", closeErr)
+	}
+	if w.gz != nil {
+		if closeErr := w.gz.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("error writing backup: %v", closeErr)
+		}
+	}
+	if w.encrypt != nil {
+		if closeErr := w.encrypt.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("error encrypting backup: %v", closeErr)
+		}
+	}
+	if closeErr := w.file.Close(); closeErr != nil && err == nil {
+		err = fmt.Errorf("error writing backup: %v", closeErr)
+	}
+
+	if err != nil {
+		if removeErr := os.Remove(w.path); removeErr != nil {
+			return fmt.Errorf("%v (and the partial backup %s couldn't be removed: %v)", err, w.path, removeErr)
+		}
+	}
+	return err
+}
+
+// writeIndex stores the index, which completes the backup
+func (w *Writer) writeIndex() error {
+	data, err := json.MarshalIndent(w.index, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling backup index: %v", err)
+	}
+	return w.writeFile(IndexFile, data)
+}
+
+// writeFile stores data under name, relative to the backup root
+func (w *Writer) writeFile(name string, data []byte) error {
+	if w.tar == nil {
+		file := filepath.Join(w.path, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+			return fmt.Errorf("error writing backup: %v", err)
+		}
+		if err := os.WriteFile(file, data, 0600); err != nil {
+			return fmt.Errorf("error writing backup: %v", err)
+		}
+		return nil
+	}
+
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0600,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	}
+	if err := w.tar.WriteHeader(header); err != nil {
+		return fmt.Errorf("error writing backup: %v", err)
+	}
+	if _, err := w.tar.Write(data); err != nil {
+		return fmt.Errorf("error writing backup: %v", err)
+	}
+	return nil
+}
+
+// manifestFile returns where an entry's manifest is stored, e.g.
+// "apps/deployments/default/web.yaml" or "core/namespaces/_cluster/team-a.yaml"
+func manifestFile(entry Entry) string {
+	group := entry.Group
+	if group == "" {
+		group = "core"
+	}
+	namespace := entry.Namespace
+	if namespace == "" {
+		namespace = "_cluster"
+	}
+	return path.Join(group, entry.Resource, namespace, entry.Name+".yaml")
+}
+
+// Strip returns a copy of obj without the fields the API server populates:
+// status, UID, resourceVersion, managedFields and the like. Owner references
+// point at UIDs that won't exist when the manifest is restored, and would
+// get it garbage collected, so they go too, as do the fields that tie a Job
+// or PVC to the objects created along with it.
+func Strip(obj *unstructured.Unstructured) *unstructured.Unstructured {
+	obj = obj.DeepCopy()
+	delete(obj.Object, "status")
+	for _, field := range []string{"uid", "resourceVersion", "managedFields", "creationTimestamp", "generation", "selfLink", "deletionTimestamp", "deletionGracePeriodSeconds", "ownerReferences"} {
+		unstructured.RemoveNestedField(obj.Object, "metadata", field)
+	}
+
+	gvk := obj.GroupVersionKind()
+	switch {
+	case gvk.Group == "batch" && gvk.Kind == "Job":
+		// The API server generates the selector from the Job's UID, unless
+		// the user set it with manualSelector
+		if manual, _, _ := unstructured.NestedBool(obj.Object, "spec", "manualSelector"); !manual {
+			unstructured.RemoveNestedField(obj.Object, "spec", "selector")
+			for _, label := range []string{"controller-uid", "batch.kubernetes.io/controller-uid"} {
+				unstructured.RemoveNestedField(obj.Object, "metadata", "labels", label)
+				unstructured.RemoveNestedField(obj.Object, "spec", "template", "metadata", "labels", label)
+			}
+		}
+	case gvk.Group == "" && gvk.Kind == "PersistentVolumeClaim":
+		// The old volume is still claimed by the deleted PVC's UID, so the
+		// restored PVC must be bound afresh
+		unstructured.RemoveNestedField(obj.Object, "spec", "volumeName")
+		for _, annotation := range []string{"pv.kubernetes.io/bind-completed", "pv.kubernetes.io/bound-by-controller"} {
+			unstructured.RemoveNestedField(obj.Object, "metadata", "annotations", annotation)
+		}
+	}
+	return obj
+}
+
+// Backup is a backup opened for reading
+type Backup struct {
+	Index Index
+	files map[string][]byte
+	root  string
+}
+
+// Open reads the backup at path, a directory or tarball written by Writer.
+// Encrypted backups are decrypted with identities.
+func Open(path string, identities []age.Identity) (*Backup, error) {
+	b := &Backup{root: path}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening backup: %v", err)
+	}
+	if !info.IsDir() {
+		if b.files, err = readTarball(path, identities); err != nil {
+			return nil, err
+		}
+	}
+
+	data, err := b.readFile(IndexFile)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a complete backup: %v", path, err)
+	}
+	if err := json.Unmarshal(data, &b.Index); err != nil {
+		return nil, fmt.Errorf("error parsing backup index: %v", err)
+	}
+	if b.Index.APIVersion != APIVersion {
+		return nil, fmt.Errorf("unsupported backup apiVersion %q", b.Index.APIVersion)
+	}
+
+	return b, nil
+}
+
+// Object returns the manifest stored for entry
+func (b *Backup) Object(entry Entry) (*unstructured.Unstructured, error) {
+	data, err := b.readFile(entry.File)
+	if err != nil {
+		return nil, err
+	}
+
+	obj := &unstructured.Unstructured{}
+	if err := yaml.Unmarshal(data, &obj.Object); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %v", entry.File, err)
+	}
+	return obj, nil
+}
+
+// readFile returns the contents of name, relative to the backup root
+func (b *Backup) readFile(name string) ([]byte, error) {
+	if b.files == nil {
+		return os.ReadFile(filepath.Join(b.root, filepath.FromSlash(name)))
+	}
+	data, ok := b.files[name]
+	if !ok {
+		return nil, fmt.Errorf("%s not found in backup", name)
+	}
+	return data, nil
+}
+
+// ageHeader starts every age encrypted file
+var ageHeader = []byte("age-encryption.org/")
+
+// gzipHeader starts every gzip file
+var gzipHeader = []byte{0x1f, 0x8b}
+
+// readTarball loads every file in a tarball, decrypting and decompressing
+// it as needed
+func readTarball(path string, identities []age.Identity) (map[string][]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening backup: %v", err)
+	}
+	defer file.Close()
+
+	// Sniff each layer rather than trusting the file name
+	reader := bufio.NewReader(file)
+	if header, _ := reader.Peek(len(ageHeader)); bytes.Equal(header, ageHeader) {
+		if len(identities) == 0 {
+			return nil, fmt.Errorf("%s is encrypted; pass the private key with --identity", path)
+		}
+		decrypted, err := age.Decrypt(reader, identities...)
+		if err != nil {
+			return nil, fmt.Errorf("error decrypting backup: %v", err)
+		}
+		reader = bufio.NewReader(decrypted)
+	}
+
+	var tarball io.Reader = reader
+	if header, _ := reader.Peek(len(gzipHeader)); bytes.Equal(header, gzipHeader) {
+		gz, err := gzip.NewReader(reader)
+		if err != nil {
+			return nil, fmt.Errorf("error reading backup: %v", err)
+		}
+		defer gz.Close()
+		tarball = gz
+	}
+
+	files := make(map[string][]byte)
+	tr := tar.NewReader(tarball)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading backup: %v", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("error reading backup: %v", err)
+		}
+		files[header.Name] = data
+	}
+	return files, nil
+}
+
+// ParseRecipients parses age public keys ("age1...") or files listing them
+func ParseRecipients(values []string) ([]age.Recipient, error) {
+	var recipients []age.Recipient
+	for _, value := range values {
+		if strings.HasPrefix(value, "age1") {
+			recipient, err := age.ParseX25519Recipient(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid recipient %q: %v", value, err)
+			}
+			recipients = append(recipients, recipient)
+			continue
+		}
+
+		file, err := os.Open(value)
+		if err != nil {
+			return nil, fmt.Errorf("error reading recipients: %v", err)
+		}
+		parsed, err := age.ParseRecipients(file)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error parsing recipients in %s: %v", value, err)
+		}
+		recipients = append(recipients, parsed...)
+	}
+	return recipients, nil
+}
+
+// ParseIdentities reads age private keys from identity files
+func ParseIdentities(paths []string) ([]age.Identity, error) {
+	var identities []age.Identity
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading identity: %v", err)
+		}
+		parsed, err := age.ParseIdentities(file)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error parsing identity in %s: %v", path, err)
+		}
+		identities = append(identities, parsed...)
+	}
+	return identities, nil
+}
@@ -3,23 +3,54 @@ package client
 import (
 	"path/filepath"
 
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/util/homedir"
 )
 
-// NewClient creates a new Kubernetes clientset
-func NewClient(kubeconfigPath, contextName string) (kubernetes.Interface, error) {
-	// If kubeconfig path not specified, use default
+// KubeconfigPath returns the kubeconfig file to use, defaulting to ~/.kube/config
+func KubeconfigPath(kubeconfigPath string) string {
 	if kubeconfigPath == "" {
 		if home := homedir.HomeDir(); home != "" {
 			kubeconfigPath = filepath.Join(home, ".kube", "config")
 		}
 	}
+	return kubeconfigPath
+}
+
+// NewClient creates a new Kubernetes clientset
+func NewClient(kubeconfigPath, contextName string) (kubernetes.Interface, error) {
+	config, err := restConfig(kubeconfigPath, contextName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create the clientset
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
 
+	return clientset, nil
+}
+
+// NewDynamicClient creates a dynamic client for resources without typed clients
+func NewDynamicClient(kubeconfigPath, contextName string) (dynamic.Interface, error) {
+	config, err := restConfig(kubeconfigPath, contextName)
+	if err != nil {
+		return nil, err
+	}
+
+	return dynamic.NewForConfig(config)
+}
+
+// restConfig builds the client configuration from the kubeconfig file
+func restConfig(kubeconfigPath, contextName string) (*rest.Config, error) {
 	// Build config from kubeconfig file
 	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
-	loadingRules.ExplicitPath = kubeconfigPath
+	loadingRules.ExplicitPath = KubeconfigPath(kubeconfigPath)
 
 	configOverrides := &clientcmd.ConfigOverrides{}
 	if contextName != "" {
@@ -32,11 +63,9 @@ func NewClient(kubeconfigPath, contextName string) (kubernetes.Interface, error)
 		return nil, err
 	}
 
-	// Create the clientset
-	clientset, err := kubernetes.NewForConfig(config)
-	if err != nil {
-		return nil, err
-	}
-
-	return clientset, nil
+	// client-go's default of 5 requests per second would cap parallel
+	// deletes; prune applies its own --max-deletes-per-second limit
+	config.QPS = 50
+	config.Burst = 100
+	return config, nil
 }
@@ -0,0 +1,155 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v2"
+	"k8s.io/client-go/util/homedir"
+)
+
+// Config is the k8s-pruner configuration file
+type Config struct {
+	// Plugins lists the exec plugins to use; plugins found on PATH are only
+	// used when named in --types
+	Plugins []Plugin `yaml:"plugins"`
+
+	// Rules prune custom resources through the dynamic client
+	Rules []Rule `yaml:"rules"`
+
+	// Policies restrict candidates with CEL expressions, like --where
+	Policies []Policy `yaml:"policies"`
+}
+
+// Policy restricts the candidates of some types with a CEL expression
+type Policy struct {
+	// Types the policy applies to; empty means all types
+	Types []string `yaml:"types"`
+	// Expression must evaluate to true for an object to be pruned
+	Expression string `yaml:"expression"`
+}
+
+// Plugin configures an exec plugin
+type Plugin struct {
+	// Name is the resource type name used in --types
+	Name string `yaml:"name"`
+	// Path is the plugin executable; defaults to k8s-pruner-<name> on PATH
+	Path string `yaml:"path"`
+	// ResourceType is the name shown in results; defaults to Name
+	ResourceType string `yaml:"resourceType"`
+	// ClusterScoped marks plugins whose resources have no namespace
+	ClusterScoped bool `yaml:"clusterScoped"`
+
+	// Group, Version and Resource, when set, make deletions go through the
+	// dynamic client instead of being delegated to the plugin
+	Group    string `yaml:"group"`
+	Version  string `yaml:"version"`
+	Resource string `yaml:"resource"`
+
+	// Timeout bounds each run of the plugin, e.g. "30s"; defaults to --plugin-timeout
+	Timeout string `yaml:"timeout"`
+}
+
+// Rule configures pruning of a custom resource, e.g.
+//
+//	rules:
+//	  - name: workflows
+//	    group: argoproj.io
+//	    version: v1alpha1
+//	    resource: workflows
+//	    match:
+//	      - field: status.phase
+//	        in: [Succeeded, Failed]
+//	    minAge: 1d
+//	    keepLast: 5
+//	    groupBy: workflows.argoproj.io/workflow-template
+type Rule struct {
+	// Name is the resource type name used in --types
+	Name string `yaml:"name"`
+	// ResourceType is the name shown in results; defaults to Name
+	ResourceType  string `yaml:"resourceType"`
+	Group         string `yaml:"group"`
+	Version       string `yaml:"version"`
+	Resource      string `yaml:"resource"`
+	ClusterScoped bool   `yaml:"clusterScoped"`
+
+	// Match lists conditions that must all hold
+	Match []RuleMatch `yaml:"match"`
+	// MatchAll must be set instead of Match for a rule to prune every
+	// object of the resource, so a forgotten match can't do that
+	MatchAll bool `yaml:"matchAll"`
+	// MinAge is a duration such as "1d"
+	MinAge string `yaml:"minAge"`
+	// KeepLast keeps the newest N matching objects per namespace and GroupBy label value
+	KeepLast int    `yaml:"keepLast"`
+	GroupBy  string `yaml:"groupBy"`
+}
+
+// RuleMatch matches a field value or a status condition
+type RuleMatch struct {
+	Field string   `yaml:"field"`
+	In    []string `yaml:"in"`
+
+	Condition string `yaml:"condition"`
+	Status    string `yaml:"status"`
+}
+
+// DefaultPath returns the default location of the configuration file
+func DefaultPath() string {
+	return filepath.Join(homedir.HomeDir(), ".k8s-pruner", "config.yaml")
+}
+
+// Load reads the configuration file at path. If path is empty the default
+// file is used, and it is not an error for it to be missing.
+func Load(path string) (*Config, error) {
+	cfg := &Config{}
+
+	explicit := path != ""
+	if !explicit {
+		path = DefaultPath()
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) && !explicit {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading config file: %v", err)
+	}
+
+	if err := yaml.UnmarshalStrict(data, cfg); err != nil {
+		return nil, fmt.Errorf("error parsing config file %s: %v", path, err)
+	}
+
+	for i, plugin := range cfg.Plugins {
+		if plugin.Name == "" {
+			return nil, fmt.Errorf("error in config file %s: plugin %d has no name", path, i+1)
+		}
+	}
+
+	for i, rule := range cfg.Rules {
+		if rule.Name == "" || rule.Version == "" || rule.Resource == "" {
+			return nil, fmt.Errorf("error in config file %s: rule %d needs a name, version and resource", path, i+1)
+		}
+		if len(rule.Match) == 0 && !rule.MatchAll {
+			return nil, fmt.Errorf("error in config file %s: rule %s has no match; set matchAll: true to prune every %s", path, rule.Name, rule.Resource)
+		}
+		if len(rule.Match) > 0 && rule.MatchAll {
+			return nil, fmt.Errorf("error in config file %s: rule %s can't have both match and matchAll", path, rule.Name)
+		}
+		for _, match := range rule.Match {
+			if (match.Field == "") == (match.Condition == "") {
+				return nil, fmt.Errorf("error in config file %s: each match of rule %s needs either a field or a condition", path, rule.Name)
+			}
+		}
+	}
+
+	for i, policy := range cfg.Policies {
+		if policy.Expression == "" {
+			return nil, fmt.Errorf("error in config file %s: policy %d has no expression", path, i+1)
+		}
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,205 @@
+package plan
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/manthan-parmar-1998/k8s-pruner/pkg/resources"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// APIVersion identifies the plan format
+	APIVersion = "k8s-pruner/v1"
+	// Kind identifies a plan document
+	Kind = "PrunePlan"
+)
+
+// Plan is a reviewed list of exact objects for prune --plan to delete
+type Plan struct {
+	APIVersion string    `json:"apiVersion"`
+	Kind       string    `json:"kind"`
+	Created    time.Time `json:"created"`
+	// Cluster is the UID of the kube-system namespace of the cluster the
+	// plan was made for
+	Cluster types.UID `json:"cluster"`
+	Items   []Item    `json:"items"`
+	// Hash covers every other field, so an edited plan is rejected
+	Hash string `json:"hash"`
+}
+
+// Item is one object in a plan
+type Item struct {
+	ResourceType      string    `json:"resourceType"`
+	Namespace         string    `json:"namespace,omitempty"`
+	Name              string    `json:"name"`
+	UID               types.UID `json:"uid"`
+	ResourceVersion   string    `json:"resourceVersion"`
+	Reason            string    `json:"reason,omitempty"`
+	CreationTimestamp time.Time `json:"creationTimestamp"`
+	// DeleteWith is the API resource an exec plugin item is deleted through
+	DeleteWith *metav1.GroupVersionResource `json:"deleteWith,omitempty"`
+	// Hash covers every other field of the item
+	Hash string `json:"hash"`
+}
+
+// New creates a plan to delete every item in results on the given cluster
+func New(results []resources.ResourceList, cluster types.UID) (*Plan, error) {
+	p := &Plan{
+		APIVersion: APIVersion,
+		Kind:       Kind,
+		Created:    time.Now().UTC(),
+		Cluster:    cluster,
+		Items:      []Item{},
+	}
+
+	for _, resourceList := range results {
+		for _, item := range resourceList.Items {
+			planItem := Item{
+				ResourceType:      resourceList.ResourceType,
+				Namespace:         item.Namespace,
+				Name:              item.Name,
+				UID:               item.UID,
+				ResourceVersion:   item.ResourceVersion,
+				Reason:            item.Reason,
+				CreationTimestamp: item.CreationTimestamp,
+				DeleteWith:        item.DeleteWith,
+			}
+			hash, err := planItem.hash()
+			if err != nil {
+				return nil, err
+			}
+			planItem.Hash = hash
+			p.Items = append(p.Items, planItem)
+		}
+	}
+
+	hash, err := p.hash()
+	if err != nil {
+		return nil, err
+	}
+	p.Hash = hash
+	return p, nil
+}
+
+// Load reads a plan and checks it hasn't been modified since it was made
+func Load(path string) (*Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading plan: %v", err)
+	}
+
+	p := &Plan{}
+	if err := json.Unmarshal(data, p); err != nil {
+		return nil, fmt.Errorf("error parsing plan %s: %v", path, err)
+	}
+	if p.APIVersion != APIVersion || p.Kind != Kind {
+		return nil, fmt.Errorf("%s is not a %s %s", path, APIVersion, Kind)
+	}
+
+	if err := p.Verify(); err != nil {
+		return nil, fmt.Errorf("plan %s: %v", path, err)
+	}
+	return p, nil
+}
+
+// Verify checks the plan's hashes against its contents
+func (p *Plan) Verify() error {
+	for _, item := range p.Items {
+		hash, err := item.hash()
+		if err != nil {
+			return err
+		}
+		if hash != item.Hash {
+			return fmt.Errorf("hash of %s %s/%s doesn't match; the plan was modified", item.ResourceType, item.Namespace, item.Name)
+		}
+	}
+
+	hash, err := p.hash()
+	if err != nil {
+		return err
+	}
+	if hash != p.Hash {
+		return fmt.Errorf("plan hash doesn't match; the plan was modified")
+	}
+	return nil
+}
+
+// ResourceLists groups the plan's items by type for DeleteUnusedResources.
+// The items keep their UID and resourceVersion, so deleting fails for any
+// object that changed since the plan was made. Items without both can't be
+// checked and are returned as skipped instead.
+func (p *Plan) ResourceLists() ([]resources.ResourceList, []resources.DeletionResult) {
+	var results []resources.ResourceList
+	var skipped []resources.DeletionResult
+	index := make(map[string]int)
+	for _, item := range p.Items {
+		if item.UID == "" || item.ResourceVersion == "" {
+			skipped = append(skipped, resources.DeletionResult{
+				ResourceType: item.ResourceType,
+				Name:         item.Name,
+				Namespace:    item.Namespace,
+				Status:       resources.StatusSkipped,
+				Reason:       "no UID and resourceVersion in the plan to check",
+			})
+			continue
+		}
+
+		i, ok := index[item.ResourceType]
+		if !ok {
+			i = len(results)
+			index[item.ResourceType] = i
+			results = append(results, resources.ResourceList{ResourceType: item.ResourceType, Items: []resources.ResourceItem{}})
+		}
+		results[i].Items = append(results[i].Items, resources.ResourceItem{
+			Name:              item.Name,
+			Namespace:         item.Namespace,
+			UID:               item.UID,
+			ResourceVersion:   item.ResourceVersion,
+			Reason:            item.Reason,
+			CreationTimestamp: item.CreationTimestamp,
+			DeleteWith:        item.DeleteWith,
+		})
+	}
+	return results, skipped
+}
+
+// ResourceTypes returns the types of the plan's items, in plan order
+func (p *Plan) ResourceTypes() []string {
+	var resourceTypes []string
+	seen := make(map[string]bool)
+	for _, item := range p.Items {
+		if !seen[item.ResourceType] {
+			seen[item.ResourceType] = true
+			resourceTypes = append(resourceTypes, item.ResourceType)
+		}
+	}
+	return resourceTypes
+}
+
+// hash returns the SHA-256 of the plan without its own hash
+func (p Plan) hash() (string, error) {
+	p.Hash = ""
+	return hashJSON(p)
+}
+
+// hash returns the SHA-256 of the item without its own hash
+func (i Item) hash() (string, error) {
+	i.Hash = ""
+	return hashJSON(i)
+}
+
+// hashJSON returns the SHA-256 of v's JSON encoding
+func hashJSON(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("error hashing plan: %v", err)
+	}
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,307 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/manthan-parmar-1998/k8s-pruner/pkg/resources"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Prefix is the executable name prefix of exec plugins
+const Prefix = "k8s-pruner-"
+
+// APIVersion is the version of the plugin protocol
+const APIVersion = "k8s-pruner/v1"
+
+// Plugin is an external detector implemented by an executable. It is called
+// with a Request as JSON on stdin and answers with JSON on stdout.
+type Plugin struct {
+	// Path is the plugin executable
+	Path string
+	// Kind is registered for the plugin in the resources registry; its Name
+	// is the resource type name used in --types
+	Kind resources.Kind
+
+	// Resource, when set, makes deletions go through the dynamic client
+	// instead of being delegated to the plugin
+	Resource *schema.GroupVersionResource
+
+	// Kubeconfig and Context are passed on so the plugin talks to the same cluster
+	Kubeconfig string
+	Context    string
+
+	// Timeout bounds each run of the plugin; 0 means no limit
+	Timeout time.Duration
+}
+
+// Request is sent to the plugin on stdin
+type Request struct {
+	APIVersion string `json:"apiVersion"`
+	// Action is "find" or "delete"
+	Action        string `json:"action"`
+	Namespace     string `json:"namespace"`
+	LabelSelector string `json:"labelSelector"`
+	// Cutoff only admits resources created before it; nil if unset
+	Cutoff *time.Time `json:"cutoff,omitempty"`
+	// After only admits resources created after it; nil if unset
+	After      *time.Time `json:"after,omitempty"`
+	Kubeconfig string     `json:"kubeconfig"`
+	Context    string     `json:"context"`
+	// Item is the resource to delete for the "delete" action
+	Item *resources.ResourceItem `json:"item,omitempty"`
+	// DeleteOptions are the options the plugin should delete Item with,
+	// including its UID and resourceVersion preconditions. Plugins must
+	// honor DryRun and leave the object in place when it is set.
+	DeleteOptions *metav1.DeleteOptions `json:"deleteOptions,omitempty"`
+}
+
+// FindResponse is the plugin's answer to a "find" request
+type FindResponse struct {
+	resources.ResourceList
+
+	// DeleteWith optionally names the resource so that deletions go through
+	// the dynamic client instead of back to the plugin. Items may also set
+	// their own.
+	DeleteWith *schema.GroupVersionResource `json:"deleteWith,omitempty"`
+}
+
+// New creates a plugin for the executable at path
+func New(name, path string) *Plugin {
+	return &Plugin{
+		Path: path,
+		Kind: resources.Kind{
+			Name:         name,
+			ResourceType: name,
+			Namespaced:   true,
+		},
+	}
+}
+
+// Discover finds k8s-pruner-<name> executables in the directories of
+// pathList (formatted like $PATH). Earlier directories win.
+func Discover(pathList string) []*Plugin {
+	var plugins []*Plugin
+	seen := make(map[string]bool)
+
+	for _, dir := range filepath.SplitList(pathList) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+
+		for _, entry := range entries {
+			if entry.IsDir() || !strings.HasPrefix(entry.Name(), Prefix) {
+				continue
+			}
+
+			name := strings.TrimSuffix(strings.TrimPrefix(entry.Name(), Prefix), ".exe")
+			if name == "" || seen[name] {
+				continue
+			}
+
+			path := filepath.Join(dir, entry.Name())
+			if !isExecutable(path) {
+				continue
+			}
+
+			seen[name] = true
+			plugins = append(plugins, New(name, path))
+		}
+	}
+
+	return plugins
+}
+
+// isExecutable reports whether path is a regular file with an execute bit set
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	return info.Mode().IsRegular() && info.Mode().Perm()&0111 != 0
+}
+
+// Name returns the resource type name
+func (p *Plugin) Name() string {
+	return p.Kind.Name
+}
+
+// Find runs the plugin and returns the resources it reports
+func (p *Plugin) Find(ctx context.Context, clients resources.Clients, opts resources.FindOptions) (resources.ResourceList, error) {
+	var response FindResponse
+	err := p.call(ctx, Request{
+		Action:        "find",
+		Namespace:     opts.Namespace,
+		LabelSelector: opts.LabelSelector,
+		Cutoff:        opts.Window.Before,
+		After:         opts.Window.After,
+	}, &response)
+	if err != nil {
+		return resources.ResourceList{}, err
+	}
+
+	// Results are always reported under the registered kind so they can be deleted
+	result := response.ResourceList
+	result.ResourceType = p.Kind.ResourceType
+	if result.Items == nil {
+		result.Items = []resources.ResourceItem{}
+	}
+
+	// Each item carries how to delete it, so saved plans delete it the same way
+	if response.DeleteWith != nil {
+		for i := range result.Items {
+			if result.Items[i].DeleteWith == nil {
+				deleteWith := metav1.GroupVersionResource(*response.DeleteWith)
+				result.Items[i].DeleteWith = &deleteWith
+			}
+		}
+	}
+
+	resource := p.Resource
+	if resource == nil {
+		resource = response.DeleteWith
+	}
+	if err := p.recordUsage(ctx, clients, opts, resource, result.Items); err != nil {
+		return resources.ResourceList{}, err
+	}
+
+	// The plugin may ignore the filters it was sent, so apply the creation
+	// window and --unused-for like the built-in detectors do
+	items := []resources.ResourceItem{}
+	for _, item := range result.Items {
+		if !opts.Window.Contains(item.CreationTimestamp) {
+			continue
+		}
+		if !opts.UnusedLongEnough(result.ResourceType, item.Namespace+"/"+item.Name) {
+			continue
+		}
+		items = append(items, item)
+	}
+	result.Items = items
+
+	return result, nil
+}
+
+// recordUsage records the objects of resource that are in the creation
+// window but weren't reported as unused in the usage history. Without a
+// resource what is in use is unknown, so no scan is recorded and
+// --unused-for never reports the plugin's items.
+func (p *Plugin) recordUsage(ctx context.Context, clients resources.Clients, opts resources.FindOptions, resource *schema.GroupVersionResource, unused []resources.ResourceItem) error {
+	if opts.History == nil || resource == nil || clients.Dynamic == nil {
+		return nil
+	}
+
+	objects, err := clients.Dynamic.Resource(*resource).Namespace(opts.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: opts.LabelSelector,
+	})
+	if err != nil {
+		return fmt.Errorf("plugin %s: error listing %s: %v", p.Name(), resource.Resource, err)
+	}
+
+	reported := make(map[string]bool)
+	for _, item := range unused {
+		reported[item.Namespace+"/"+item.Name] = true
+	}
+
+	used := make(map[string]bool)
+	for _, obj := range objects.Items {
+		key := obj.GetNamespace() + "/" + obj.GetName()
+		if !reported[key] && opts.Window.Contains(obj.GetCreationTimestamp().Time) {
+			used[key] = true
+		}
+	}
+
+	opts.RecordUsage(p.Kind.ResourceType, used)
+	return nil
+}
+
+// TracksUsage reports that the plugin's items honor --unused-for
+func (p *Plugin) TracksUsage() bool {
+	return true
+}
+
+// Delete deletes an item through the dynamic client if the plugin named its
+// resource in the find response or the config, and delegates to the plugin
+// otherwise
+func (p *Plugin) Delete(ctx context.Context, clients resources.Clients, item resources.ResourceItem, opts metav1.DeleteOptions) error {
+	if item.DeleteWith != nil {
+		return resources.DeleteDynamic(ctx, clients, schema.GroupVersionResource(*item.DeleteWith), item, opts)
+	}
+	if p.Resource != nil {
+		return resources.DeleteDynamic(ctx, clients, *p.Resource, item, opts)
+	}
+
+	return p.call(ctx, Request{
+		Action:        "delete",
+		Namespace:     item.Namespace,
+		Item:          &item,
+		DeleteOptions: &opts,
+	}, nil)
+}
+
+// RequiredPermissions returns the RBAC rules needed for the usage history
+// and dynamic deletion. What the plugin itself needs is up to the plugin.
+func (p *Plugin) RequiredPermissions() []rbacv1.PolicyRule {
+	if p.Resource == nil {
+		return nil
+	}
+	return []rbacv1.PolicyRule{
+		{APIGroups: []string{p.Resource.Group}, Resources: []string{p.Resource.Resource}, Verbs: []string{"list", "delete"}},
+	}
+}
+
+// call runs the plugin with request on stdin and decodes stdout into response
+func (p *Plugin) call(ctx context.Context, request Request, response interface{}) error {
+	request.APIVersion = APIVersion
+	request.Kubeconfig = p.Kubeconfig
+	request.Context = p.Context
+
+	input, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("plugin %s: error marshaling request: %v", p.Name(), err)
+	}
+
+	// A hung plugin would otherwise hang the whole prune
+	if p.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.Timeout)
+		defer cancel()
+	}
+
+	var stdout, stderr bytes.Buffer
+	command := exec.CommandContext(ctx, p.Path)
+	command.Stdin = bytes.NewReader(input)
+	command.Stdout = &stdout
+	command.Stderr = &stderr
+	// Don't wait on children of the plugin that still hold its output open
+	command.WaitDelay = time.Second
+
+	if err := command.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("plugin %s %s timed out after %s", p.Name(), request.Action, p.Timeout)
+		}
+		message := strings.TrimSpace(stderr.String())
+		if message == "" {
+			message = err.Error()
+		}
+		return fmt.Errorf("plugin %s %s failed: %s", p.Name(), request.Action, message)
+	}
+
+	if response == nil {
+		return nil
+	}
+	if err := json.Unmarshal(stdout.Bytes(), response); err != nil {
+		return fmt.Errorf("plugin %s: invalid response: %v", p.Name(), err)
+	}
+	return nil
+}
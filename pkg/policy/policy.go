@@ -0,0 +1,125 @@
+package policy
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/cel-go/cel"
+)
+
+// env declares the variables available to expressions
+var env *cel.Env
+
+func init() {
+	var err error
+	env, err = cel.NewEnv(
+		// object is the full object as it is returned by the API server
+		cel.Variable("object", cel.MapType(cel.StringType, cel.DynType)),
+		// age is the time since the object was created
+		cel.Variable("age", cel.DurationType),
+		// referencedBy lists the objects pointing at it, e.g. "Deployment/team-a/web"
+		cel.Variable("referencedBy", cel.ListType(cel.StringType)),
+		// namespaceLabels are the labels of the object's namespace
+		cel.Variable("namespaceLabels", cel.MapType(cel.StringType, cel.StringType)),
+		// size is the size of the object's JSON encoding in bytes
+		cel.Variable("size", cel.IntType),
+		// Optional field selection reads fields that may be missing, e.g.
+		// object.metadata.?labels.?team.orValue("")
+		cel.OptionalTypes(),
+	)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Expression is a compiled and type-checked CEL expression that selects
+// candidates, e.g. `object.type == "Opaque" && size > 100000`
+type Expression struct {
+	source  string
+	program cel.Program
+}
+
+// Variables are the inputs of an expression
+type Variables struct {
+	Object          map[string]interface{}
+	Age             time.Duration
+	ReferencedBy    []string
+	NamespaceLabels map[string]string
+	Size            int
+}
+
+// Compile parses and type-checks source, which must evaluate to a bool
+func Compile(source string) (*Expression, error) {
+	ast, issues := env.Compile(source)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("invalid expression %q: %v", source, issues.Err())
+	}
+	if ast.OutputType() != cel.BoolType {
+		return nil, fmt.Errorf("invalid expression %q: must evaluate to bool, not %s", source, ast.OutputType())
+	}
+
+	program, err := env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %v", source, err)
+	}
+
+	return &Expression{source: source, program: program}, nil
+}
+
+// String returns the expression source
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Matches evaluates the expression
+func (e *Expression) Matches(vars Variables) (bool, error) {
+	referencedBy := vars.ReferencedBy
+	if referencedBy == nil {
+		referencedBy = []string{}
+	}
+	namespaceLabels := vars.NamespaceLabels
+	if namespaceLabels == nil {
+		namespaceLabels = map[string]string{}
+	}
+
+	value, _, err := e.program.Eval(map[string]interface{}{
+		"object":          vars.Object,
+		"age":             vars.Age,
+		"referencedBy":    referencedBy,
+		"namespaceLabels": namespaceLabels,
+		"size":            vars.Size,
+	})
+	if err != nil {
+		return false, fmt.Errorf("error evaluating %q: %v (read fields the object may not have with has() or ?., e.g. object.metadata.?labels.?team.orValue(\"\"))", e.source, err)
+	}
+
+	matched, ok := value.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("error evaluating %q: result is %v, not a bool", e.source, value)
+	}
+	return matched, nil
+}
+
+// Filters holds the expressions to apply per resource type (as named in
+// --types). Every expression that applies to a type must match.
+type Filters struct {
+	All     []*Expression
+	PerType map[string][]*Expression
+}
+
+// Add adds an expression for resourceType, or for all types if it is empty
+func (f *Filters) Add(resourceType string, expression *Expression) {
+	if resourceType == "" {
+		f.All = append(f.All, expression)
+		return
+	}
+	if f.PerType == nil {
+		f.PerType = map[string][]*Expression{}
+	}
+	f.PerType[resourceType] = append(f.PerType[resourceType], expression)
+}
+
+// For returns the expressions that apply to resourceType
+func (f Filters) For(resourceType string) []*Expression {
+	return append(append([]*Expression(nil), f.All...), f.PerType[resourceType]...)
+}
@@ -2,89 +2,117 @@ package resources
 
 import (
 	"context"
-	"time"
 
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-// FindUnusedConfigMaps finds ConfigMaps that are not mounted in any Pod
-func (d *ResourceDetector) FindUnusedConfigMaps(namespace string, olderThan *time.Time, labelSelector string) (ResourceList, error) {
-	ctx := context.Background()
+// configMapDetector finds ConfigMaps that no Pod or workload references
+type configMapDetector struct{}
+
+// Name returns the canonical type name
+func (configMapDetector) Name() string {
+	return "configmaps"
+}
+
+// Find returns ConfigMaps that no Pod or workload references
+func (configMapDetector) Find(ctx context.Context, clients Clients, opts FindOptions) (ResourceList, error) {
 	result := ResourceList{
 		ResourceType: "ConfigMaps",
 		Items:        []ResourceItem{},
 	}
 
 	// Get all ConfigMaps
-	configMaps, err := d.client.CoreV1().ConfigMaps(namespace).List(ctx, metav1.ListOptions{
-		LabelSelector: labelSelector,
+	configMaps, err := clients.Kube.CoreV1().ConfigMaps(opts.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: opts.LabelSelector,
 	})
 	if err != nil {
 		return result, err
 	}
 
-	// Get all Pods
-	pods, err := d.client.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	// Track which ConfigMaps Pods and workload templates reference
+	references, err := BuildReferenceIndex(ctx, clients.Kube, opts.Namespace)
 	if err != nil {
 		return result, err
 	}
+	usedConfigMaps := references.referenced("ConfigMap")
 
-	// Track which ConfigMaps are in use
-	usedConfigMaps := make(map[string]bool)
-
-	// Check ConfigMap usage in Pods
-	for _, pod := range pods.Items {
-		// Check volume mounts
-		for _, volume := range pod.Spec.Volumes {
-			if volume.ConfigMap != nil {
-				key := pod.Namespace + "/" + volume.ConfigMap.Name
-				usedConfigMaps[key] = true
-			}
-		}
-
-		// Check environment variables
-		for _, container := range pod.Spec.Containers {
-			for _, env := range container.Env {
-				if env.ValueFrom != nil && env.ValueFrom.ConfigMapKeyRef != nil {
-					key := pod.Namespace + "/" + env.ValueFrom.ConfigMapKeyRef.Name
-					usedConfigMaps[key] = true
-				}
-			}
-
-			// Check envFrom
-			for _, envFrom := range container.EnvFrom {
-				if envFrom.ConfigMapRef != nil {
-					key := pod.Namespace + "/" + envFrom.ConfigMapRef.Name
-					usedConfigMaps[key] = true
-				}
-			}
-		}
-	}
+	// Remember what is in use for future --unused-for checks
+	opts.RecordUsage("ConfigMaps", usedConfigMaps)
 
 	// Add unused ConfigMaps to result
 	for _, cm := range configMaps.Items {
 		key := cm.Namespace + "/" + cm.Name
 
 		// Skip kube-system ConfigMaps and those with special prefixes
-		if cm.Namespace == "kube-system" ||
-			cm.Name == "kube-root-ca.crt" {
+		if isProtectedConfigMap(cm) {
 			continue
 		}
 
 		// Check if ConfigMap is unused
 		if !usedConfigMaps[key] {
-			// Check age if filter is provided
-			if olderThan != nil && cm.CreationTimestamp.Time.After(*olderThan) {
+			// Check the creation time window
+			if !opts.Window.Contains(cm.CreationTimestamp.Time) {
 				continue
 			}
 
-			result.Items = append(result.Items, ResourceItem{
-				Name:      cm.Name,
-				Namespace: cm.Namespace,
-				Age:       cm.CreationTimestamp.Time,
-			})
+			// Check that it also stayed unused for the --unused-for window
+			if !opts.UnusedLongEnough("ConfigMaps", key) {
+				continue
+			}
+
+			item := NewResourceItem(&cm)
+			item.Reason = "not referenced by any pod or workload"
+			item.Reasons = []string{ReasonUnreferenced}
+			result.Items = append(result.Items, item)
 		}
 	}
 
 	return result, nil
 }
+
+// Explain checks a single ConfigMap the way Find does
+func (d configMapDetector) Explain(ctx context.Context, clients Clients, item ResourceItem) ([]Check, error) {
+	references, err := BuildReferenceIndex(ctx, clients.Kube, item.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	return d.explainReferences(ctx, clients, item, references)
+}
+
+// explainReferences checks a single ConfigMap against references
+func (configMapDetector) explainReferences(ctx context.Context, clients Clients, item ResourceItem, references ReferenceIndex) ([]Check, error) {
+	cm, err := clients.Kube.CoreV1().ConfigMaps(item.Namespace).Get(ctx, item.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	checks := []Check{{
+		Name:   "system",
+		Passed: !isProtectedConfigMap(*cm),
+		Detail: "ConfigMaps in kube-system and kube-root-ca.crt are never pruned",
+	}}
+
+	// Find the Pods and workloads using it
+	users := references.ReferencedBy(ObjectRef{Kind: "ConfigMap", Namespace: cm.Namespace, Name: cm.Name})
+
+	return append(checks, usageCheck("not referenced by any pod or workload", users)), nil
+}
+
+// Delete deletes a single ConfigMap
+func (configMapDetector) Delete(ctx context.Context, clients Clients, item ResourceItem, opts metav1.DeleteOptions) error {
+	return clients.Kube.CoreV1().ConfigMaps(item.Namespace).Delete(ctx, item.Name, opts)
+}
+
+// RequiredPermissions returns the RBAC rules Find and Delete need
+func (configMapDetector) RequiredPermissions() []rbacv1.PolicyRule {
+	return append([]rbacv1.PolicyRule{
+		{APIGroups: []string{""}, Resources: []string{"configmaps"}, Verbs: []string{"list", "delete"}},
+	}, referencePermissions()...)
+}
+
+// isProtectedConfigMap returns true for ConfigMaps that are never pruned
+func isProtectedConfigMap(cm corev1.ConfigMap) bool {
+	return cm.Namespace == "kube-system" || cm.Name == "kube-root-ca.crt"
+}
@@ -0,0 +1,90 @@
+package resources
+
+import (
+	"context"
+	"sort"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DanglingReferences finds the references in namespace ("" for all) to
+// ConfigMaps, Secrets, PVCs and ServiceAccounts that don't exist. Missing
+// objects marked optional don't break anything and are only included if
+// includeOptional is set.
+func (d *ResourceDetector) DanglingReferences(namespace string, includeOptional bool) ([]Reference, error) {
+	ctx := context.Background()
+	client := d.clients.Kube
+
+	index, err := BuildReferenceIndex(ctx, client, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	// Collect the objects that exist
+	exists := make(map[ObjectRef]bool)
+	add := func(kind string, obj metav1.Object) {
+		exists[ObjectRef{Kind: kind, Namespace: obj.GetNamespace(), Name: obj.GetName()}] = true
+	}
+
+	configMaps, err := client.CoreV1().ConfigMaps(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range configMaps.Items {
+		add("ConfigMap", &configMaps.Items[i])
+	}
+
+	secrets, err := client.CoreV1().Secrets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range secrets.Items {
+		add("Secret", &secrets.Items[i])
+	}
+
+	pvcs, err := client.CoreV1().PersistentVolumeClaims(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range pvcs.Items {
+		add("PersistentVolumeClaim", &pvcs.Items[i])
+	}
+
+	serviceAccounts, err := client.CoreV1().ServiceAccounts(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range serviceAccounts.Items {
+		add("ServiceAccount", &serviceAccounts.Items[i])
+	}
+
+	// Keep the references to missing objects
+	dangling := []Reference{}
+	seen := make(map[Reference]bool)
+	for target, refs := range index {
+		if exists[target] {
+			continue
+		}
+		for _, ref := range refs {
+			if seen[ref] || (ref.Optional && !includeOptional) {
+				continue
+			}
+			seen[ref] = true
+			dangling = append(dangling, ref)
+		}
+	}
+
+	// Group by missing object, for stable output
+	sort.Slice(dangling, func(i, j int) bool {
+		a, b := dangling[i], dangling[j]
+		if a.To != b.To {
+			return a.To.String() < b.To.String()
+		}
+		if a.From != b.From {
+			return a.From.String() < b.From.String()
+		}
+		return a.Via < b.Via
+	})
+
+	return dangling, nil
+}
@@ -0,0 +1,77 @@
+package resources
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+)
+
+// NewResourceDetector creates a new ResourceDetector with only a typed
+// client. Without a dynamic client, custom resource rules, backups, explain
+// and --where are unavailable.
+//
+// Deprecated: use NewResourceDetectorWithClients.
+func NewResourceDetector(client kubernetes.Interface) *ResourceDetector {
+	return NewResourceDetectorWithClients(Clients{Kube: client})
+}
+
+// FindUnusedConfigMaps finds ConfigMaps that no Pod or workload references.
+//
+// Deprecated: use FindAllUnusedResources with the "configmaps" type.
+func (d *ResourceDetector) FindUnusedConfigMaps(namespace string, olderThan *time.Time, labelSelector string) (ResourceList, error) {
+	return d.findKind("configmaps", namespace, olderThan, labelSelector)
+}
+
+// FindUnusedSecrets finds Secrets that no Pod, workload or ServiceAccount references.
+//
+// Deprecated: use FindAllUnusedResources with the "secrets" type.
+func (d *ResourceDetector) FindUnusedSecrets(namespace string, olderThan *time.Time, labelSelector string) (ResourceList, error) {
+	return d.findKind("secrets", namespace, olderThan, labelSelector)
+}
+
+// FindUnusedPVCs finds PVCs that no Pod or workload mounts.
+//
+// Deprecated: use FindAllUnusedResources with the "pvcs" type.
+func (d *ResourceDetector) FindUnusedPVCs(namespace string, olderThan *time.Time, labelSelector string) (ResourceList, error) {
+	return d.findKind("pvcs", namespace, olderThan, labelSelector)
+}
+
+// FindCompletedPods finds Pods that succeeded or failed and have no controller.
+//
+// Deprecated: use FindAllUnusedResources with the "pods" type.
+func (d *ResourceDetector) FindCompletedPods(namespace string, olderThan *time.Time, labelSelector string) (ResourceList, error) {
+	return d.findKind("pods", namespace, olderThan, labelSelector)
+}
+
+// FindCompletedJobs finds completed Jobs that no CronJob owns.
+//
+// Deprecated: use FindAllUnusedResources with the "jobs" type.
+func (d *ResourceDetector) FindCompletedJobs(namespace string, olderThan *time.Time, labelSelector string) (ResourceList, error) {
+	return d.findKind("jobs", namespace, olderThan, labelSelector)
+}
+
+// FindUnusedNamespaces finds namespaces without workloads, Services,
+// ConfigMaps or Secrets.
+//
+// Deprecated: use FindAllUnusedResources with the "namespaces" type.
+func (d *ResourceDetector) FindUnusedNamespaces(olderThan *time.Time, labelSelector string) (ResourceList, error) {
+	return d.findKind("namespaces", "", olderThan, labelSelector)
+}
+
+// findKind runs the detector of one kind with the filters of the
+// deprecated FindUnused* methods
+func (d *ResourceDetector) findKind(name, namespace string, olderThan *time.Time, labelSelector string) (ResourceList, error) {
+	kind, err := LookupKind(name)
+	if err != nil {
+		return ResourceList{}, err
+	}
+
+	return kind.detector.Find(context.Background(), d.clients, FindOptions{
+		Namespace:     namespace,
+		Window:        TimeWindow{Before: olderThan},
+		LabelSelector: labelSelector,
+		History:       d.history,
+		UnusedSince:   d.unusedSince,
+	})
+}
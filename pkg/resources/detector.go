@@ -2,17 +2,152 @@ package resources
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/manthan-parmar-1998/k8s-pruner/pkg/backup"
+	"github.com/manthan-parmar-1998/k8s-pruner/pkg/policy"
+	"github.com/manthan-parmar-1998/k8s-pruner/pkg/state"
+	"golang.org/x/time/rate"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
 )
 
 // ResourceItem represents a Kubernetes resource
 type ResourceItem struct {
-	Name      string    `json:"name"`
-	Namespace string    `json:"namespace"`
-	Age       time.Time `json:"age"`
+	APIVersion        string    `json:"apiVersion,omitempty"`
+	Kind              string    `json:"kind,omitempty"`
+	Name              string    `json:"name"`
+	Namespace         string    `json:"namespace"`
+	CreationTimestamp time.Time `json:"creationTimestamp"`
+
+	// UID and ResourceVersion are captured at detection time so a delete
+	// never hits an object that was recreated or changed since
+	UID             types.UID `json:"uid,omitempty"`
+	ResourceVersion string    `json:"resourceVersion,omitempty"`
+
+	Labels map[string]string `json:"labels,omitempty"`
+	// Annotations holds only the annotations of interest, see interestingAnnotations
+	Annotations     map[string]string       `json:"annotations,omitempty"`
+	OwnerReferences []metav1.OwnerReference `json:"ownerReferences,omitempty"`
+
+	// Reason says why the detector considers the object unused, and
+	// Reasons says the same with the Reason* codes
+	Reason  string   `json:"reason,omitempty"`
+	Reasons []string `json:"reasons,omitempty"`
+
+	// Size estimates the object's size as the length of its JSON encoding
+	Size int `json:"size,omitempty"`
+
+	// CandidateSince is when mark first saw the object unused, if it did
+	CandidateSince *time.Time `json:"candidateSince,omitempty"`
+
+	// DeleteWith, set by exec plugins, names the API resource the object is
+	// deleted through with the dynamic client
+	DeleteWith *metav1.GroupVersionResource `json:"deleteWith,omitempty"`
+}
+
+// Machine-readable reasons reported in ResourceItem.Reasons
+const (
+	// ReasonUnreferenced means no Pod or workload, or ServiceAccount for Secrets, references the object
+	ReasonUnreferenced = "Unreferenced"
+	// ReasonUnmounted means no Pod or workload mounts the PVC
+	ReasonUnmounted = "Unmounted"
+	// ReasonPodSucceeded and ReasonPodFailed give the phase of a finished Pod
+	ReasonPodSucceeded = "PodSucceeded"
+	ReasonPodFailed    = "PodFailed"
+	// ReasonNoController means no controller owns the Pod to clean it up
+	ReasonNoController = "NoController"
+	// ReasonJobCompleted means the Job completed
+	ReasonJobCompleted = "JobCompleted"
+	// ReasonNoCronJob means no CronJob owns the Job to clean it up
+	ReasonNoCronJob = "NoCronJob"
+	// ReasonEmptyNamespace means the namespace contains no workloads, Services, ConfigMaps or Secrets
+	ReasonEmptyNamespace = "EmptyNamespace"
+	// ReasonRuleMatched means the object matches a custom resource rule
+	ReasonRuleMatched = "RuleMatched"
+	// ReasonNotAmongNewest means the object isn't among the newest ones a rule keeps
+	ReasonNotAmongNewest = "NotAmongNewest"
+)
+
+// interestingAnnotations are copied to ResourceItem.Annotations along with
+// every k8s-pruner.io/ annotation; they tell who manages an object
+var interestingAnnotations = []string{
+	"meta.helm.sh/release-name",
+	"meta.helm.sh/release-namespace",
+	"argocd.argoproj.io/tracking-id",
+	"kustomize.toolkit.fluxcd.io/name",
+	"kustomize.toolkit.fluxcd.io/namespace",
+}
+
+// NewResourceItem creates a ResourceItem describing obj
+func NewResourceItem(obj metav1.Object) ResourceItem {
+	item := ResourceItem{
+		Name:              obj.GetName(),
+		Namespace:         obj.GetNamespace(),
+		CreationTimestamp: obj.GetCreationTimestamp().Time,
+		UID:               obj.GetUID(),
+		ResourceVersion:   obj.GetResourceVersion(),
+		Labels:            obj.GetLabels(),
+		OwnerReferences:   obj.GetOwnerReferences(),
+		CandidateSince:    candidateSince(obj.GetAnnotations()),
+	}
+
+	for key, value := range obj.GetAnnotations() {
+		if strings.HasPrefix(key, "k8s-pruner.io/") || containsName(interestingAnnotations, key) {
+			if item.Annotations == nil {
+				item.Annotations = make(map[string]string)
+			}
+			item.Annotations[key] = value
+		}
+	}
+
+	if runtimeObj, ok := obj.(runtime.Object); ok {
+		// Typed objects from lists don't have their kind set
+		gvk := runtimeObj.GetObjectKind().GroupVersionKind()
+		if gvk.Empty() {
+			if gvks, _, err := scheme.Scheme.ObjectKinds(runtimeObj); err == nil && len(gvks) > 0 {
+				gvk = gvks[0]
+			}
+		}
+		item.APIVersion, item.Kind = gvk.ToAPIVersionAndKind()
+
+		if data, err := json.Marshal(runtimeObj); err == nil {
+			item.Size = len(data)
+		}
+	}
+
+	return item
+}
+
+// UnmarshalJSON also accepts "age", the name of creationTimestamp before
+// k8s-pruner/v1 reports, so existing exec plugins keep working
+func (i *ResourceItem) UnmarshalJSON(data []byte) error {
+	type plain ResourceItem
+	var item struct {
+		plain
+		Age *time.Time `json:"age"`
+	}
+	if err := json.Unmarshal(data, &item); err != nil {
+		return err
+	}
+
+	*i = ResourceItem(item.plain)
+	if i.CreationTimestamp.IsZero() && item.Age != nil {
+		i.CreationTimestamp = *item.Age
+	}
+	return nil
 }
 
 // ResourceList represents a list of resources of a specific type
@@ -21,43 +156,114 @@ type ResourceList struct {
 	Items        []ResourceItem `json:"items"`
 }
 
+// Detector finds and deletes unused resources of one kind. The built-in
+// kinds are Detectors too; detectors from other Go modules are added with
+// Register and are treated exactly like them.
+type Detector interface {
+	// Name returns the canonical type name used in --types, e.g. "configmaps"
+	Name() string
+
+	// Find returns the resources matching opts that are safe to remove
+	Find(ctx context.Context, clients Clients, opts FindOptions) (ResourceList, error)
+
+	// Delete deletes a single item previously returned by Find
+	Delete(ctx context.Context, clients Clients, item ResourceItem, opts metav1.DeleteOptions) error
+
+	// RequiredPermissions returns the RBAC rules Find and Delete need
+	RequiredPermissions() []rbacv1.PolicyRule
+}
+
+// Clients holds the API clients available to a Detector
+type Clients struct {
+	Kube kubernetes.Interface
+	// Dynamic reaches resources without a typed client, e.g. CRDs
+	Dynamic dynamic.Interface
+}
+
+// FindOptions holds the filters passed to Detector.Find
+type FindOptions struct {
+	// Namespace to search, or "" for all namespaces
+	Namespace string
+	// Window restricts resources by creation time
+	Window TimeWindow
+	// LabelSelector restricts resources by label
+	LabelSelector string
+
+	// History records when objects were last seen referenced; nil if disabled
+	History *state.Store
+	// UnusedSince, when set with History, requires objects to have been
+	// unreferenced in every scan since then
+	UnusedSince *time.Time
+}
+
 // ResourceDetector handles detection of unused resources
 type ResourceDetector struct {
-	client kubernetes.Interface
+	clients Clients
+
+	// history and unusedSince are set by UseHistory
+	history     *state.Store
+	unusedSince *time.Time
+
+	// filters are set by UseFilters
+	filters policy.Filters
 }
 
-// NewResourceDetector creates a new ResourceDetector
-func NewResourceDetector(client kubernetes.Interface) *ResourceDetector {
-	return &ResourceDetector{client: client}
+// NewResourceDetectorWithClients creates a new ResourceDetector
+func NewResourceDetectorWithClients(clients Clients) *ResourceDetector {
+	return &ResourceDetector{clients: clients}
 }
 
-// FindAllUnusedResources finds all unused resources of the specified types
-func (d *ResourceDetector) FindAllUnusedResources(namespace string, olderThan *time.Time, types []string, labelSelector string) ([]ResourceList, error) {
+// ClusterID identifies the cluster by the UID of its kube-system namespace,
+// which stays the same across API server addresses and kubeconfig contexts
+func (d *ResourceDetector) ClusterID() (types.UID, error) {
+	ns, err := d.clients.Kube.CoreV1().Namespaces().Get(context.Background(), "kube-system", metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("error identifying the cluster: %v", err)
+	}
+	return ns.UID, nil
+}
+
+// FindAllUnusedResources finds all unused resources of the specified types,
+// applying each type's creation time window. Types may be given by any name
+// known to LookupKind; no types means every registered kind.
+func (d *ResourceDetector) FindAllUnusedResources(namespace string, windows TimeWindows, types []string, labelSelector string) ([]ResourceList, error) {
 	var results []ResourceList
+	ctx := context.Background()
+	scope := &filterScope{namespace: namespace}
 
-	for _, resourceType := range types {
-		var resourceList ResourceList
-		var err error
-
-		switch resourceType {
-		case "configmaps":
-			resourceList, err = d.FindUnusedConfigMaps(namespace, olderThan, labelSelector)
-		case "secrets":
-			resourceList, err = d.FindUnusedSecrets(namespace, olderThan, labelSelector)
-		case "pvcs":
-			resourceList, err = d.FindUnusedPVCs(namespace, olderThan, labelSelector)
-		case "pods":
-			resourceList, err = d.FindCompletedPods(namespace, olderThan, labelSelector)
-		case "jobs":
-			resourceList, err = d.FindCompletedJobs(namespace, olderThan, labelSelector)
-		case "namespaces":
-			if namespace == "" {
-				resourceList, err = d.FindUnusedNamespaces(olderThan, labelSelector)
-			}
+	selectedKinds, err := LookupKinds(types)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, kind := range selectedKinds {
+		// Cluster-scoped kinds don't apply when a namespace is targeted
+		if !kind.Namespaced && namespace != "" {
+			continue
 		}
 
+		resourceList, err := kind.detector.Find(ctx, d.clients, FindOptions{
+			Namespace:     namespace,
+			Window:        windows.For(kind.Name),
+			LabelSelector: labelSelector,
+			History:       d.history,
+			UnusedSince:   d.unusedSince,
+		})
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("%s: %v", kind.Name, err)
+		}
+
+		// Apply --where expressions. Those for every type need the objects
+		// from the API, so they skip kinds that don't declare their resource.
+		expressions := d.filters.For(kind.Name)
+		if kind.Resource.Resource == "" {
+			expressions = d.filters.PerType[kind.Name]
+		}
+		if len(expressions) > 0 && len(resourceList.Items) > 0 {
+			resourceList, err = d.filterCandidates(ctx, scope, kind, expressions, labelSelector, resourceList)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", kind.Name, err)
+			}
 		}
 
 		if len(resourceList.Items) > 0 {
@@ -68,37 +274,212 @@ func (d *ResourceDetector) FindAllUnusedResources(namespace string, olderThan *t
 	return results, nil
 }
 
-// DeleteUnusedResources deletes the specified unused resources
-func (d *ResourceDetector) DeleteUnusedResources(resources []ResourceList) (int, error) {
-	deletedCount := 0
+// PruneOptions controls how DeleteUnusedResources deletes
+type PruneOptions struct {
+	// Concurrency is the number of deletes in flight; values below 1 mean 1
+	Concurrency int
+	// MaxPerSecond limits the rate of deletes; 0 means unlimited
+	MaxPerSecond float64
+
+	// PropagationPolicy decides what happens to dependents; nil leaves it to the server
+	PropagationPolicy *metav1.DeletionPropagation
+	// GracePeriodSeconds overrides the objects' grace period; nil keeps their default
+	GracePeriodSeconds *int64
+
+	// DryRun sends every delete as a server-side dry run, so admission,
+	// RBAC and preconditions are checked but nothing is removed
+	DryRun bool
+
+	// Backup, when set, receives the manifest of every item and is closed
+	// before anything is deleted, so the backup is complete even if the
+	// prune is interrupted. Items that can't be backed up are not deleted.
+	Backup *backup.Writer
+}
+
+// deleteOptions builds the options for deleting item, with preconditions
+// that make the delete fail if the object changed since it was found
+func (o PruneOptions) deleteOptions(item ResourceItem) metav1.DeleteOptions {
+	opts := metav1.DeleteOptions{
+		PropagationPolicy:  o.PropagationPolicy,
+		GracePeriodSeconds: o.GracePeriodSeconds,
+	}
+	if o.DryRun {
+		opts.DryRun = []string{metav1.DryRunAll}
+	}
+
+	if item.UID != "" || item.ResourceVersion != "" {
+		opts.Preconditions = &metav1.Preconditions{}
+		if item.UID != "" {
+			uid := item.UID
+			opts.Preconditions.UID = &uid
+		}
+		if item.ResourceVersion != "" {
+			resourceVersion := item.ResourceVersion
+			opts.Preconditions.ResourceVersion = &resourceVersion
+		}
+	}
+
+	return opts
+}
+
+// maxThrottleRetries is how often a delete is retried after the API server
+// answers 429 Too Many Requests
+const maxThrottleRetries = 5
+
+// deletion is a single item queued for deletion
+type deletion struct {
+	resourceType string
+	kind         Kind
+	known        bool
+	item         ResourceItem
+}
+
+// DeleteUnusedResources deletes the specified unused resources with a
+// bounded pool of workers. It attempts every item, even after failures, and
+// reports the outcome of each, ordered by type and then namespace and name.
+// With opts.DryRun the outcomes are what would have happened. With
+// opts.Backup every item is backed up, and the backup closed, before the
+// first delete.
+func (d *ResourceDetector) DeleteUnusedResources(resources []ResourceList, opts PruneOptions) DeletionReport {
 	ctx := context.Background() // Create a context
 
+	// Queue items in a stable order so the report reads the same every run
+	var queue []deletion
 	for _, resourceList := range resources {
-		for _, item := range resourceList.Items {
-			var err error
-
-			switch resourceList.ResourceType {
-			case "ConfigMaps":
-				err = d.client.CoreV1().ConfigMaps(item.Namespace).Delete(ctx, item.Name, metav1.DeleteOptions{})
-			case "Secrets":
-				err = d.client.CoreV1().Secrets(item.Namespace).Delete(ctx, item.Name, metav1.DeleteOptions{})
-			case "PersistentVolumeClaims":
-				err = d.client.CoreV1().PersistentVolumeClaims(item.Namespace).Delete(ctx, item.Name, metav1.DeleteOptions{})
-			case "Pods":
-				err = d.client.CoreV1().Pods(item.Namespace).Delete(ctx, item.Name, metav1.DeleteOptions{})
-			case "Jobs":
-				err = d.client.BatchV1().Jobs(item.Namespace).Delete(ctx, item.Name, metav1.DeleteOptions{})
-			case "Namespaces":
-				err = d.client.CoreV1().Namespaces().Delete(ctx, item.Name, metav1.DeleteOptions{})
+		kind, known := KindForResourceType(resourceList.ResourceType)
+
+		items := append([]ResourceItem(nil), resourceList.Items...)
+		sort.SliceStable(items, func(i, j int) bool {
+			if items[i].Namespace != items[j].Namespace {
+				return items[i].Namespace < items[j].Namespace
 			}
+			return items[i].Name < items[j].Name
+		})
 
-			if err != nil {
-				return deletedCount, err
+		for _, item := range items {
+			queue = append(queue, deletion{resourceType: resourceList.ResourceType, kind: kind, known: known, item: item})
+		}
+	}
+
+	limiter := rate.NewLimiter(rate.Inf, 1)
+	if opts.MaxPerSecond > 0 {
+		limiter = rate.NewLimiter(rate.Limit(opts.MaxPerSecond), 1)
+	}
+
+	concurrency := opts.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	// Each worker writes only the results of the indexes it takes
+	results := make([]DeletionResult, len(queue))
+	done := make([]bool, len(queue))
+
+	// Back everything up and finish the backup before the first delete
+	if opts.Backup != nil {
+		for i, del := range queue {
+			if err := d.backupItem(ctx, opts.Backup, del); err != nil {
+				results[i] = newDeletionResult(del.resourceType, del.item, err)
+				done[i] = true
 			}
+		}
+
+		// Without an index the backup can't be restored, so delete nothing
+		if err := opts.Backup.Close(); err != nil {
+			for i, del := range queue {
+				if !done[i] {
+					results[i] = newDeletionResult(del.resourceType, del.item, fmt.Errorf("backup failed: %v", err))
+				}
+			}
+			return DeletionReport{DryRun: opts.DryRun, Results: results}
+		}
+	}
 
-			deletedCount++
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = newDeletionResult(queue[i].resourceType, queue[i].item, d.deleteItem(ctx, limiter, queue[i], opts))
+			}
+		}()
+	}
+	for i := range queue {
+		if !done[i] {
+			indexes <- i
 		}
 	}
+	close(indexes)
+	wg.Wait()
+
+	return DeletionReport{DryRun: opts.DryRun, Results: results}
+}
+
+// backupItem writes the current manifest of one item to the backup. An item
+// that is already gone comes back as a NotFound error.
+func (d *ResourceDetector) backupItem(ctx context.Context, writer *backup.Writer, del deletion) error {
+	if !del.known {
+		return fmt.Errorf("unknown resource type %q", del.resourceType)
+	}
+
+	obj, err := d.getObject(ctx, del.kind, del.item)
+	if apierrors.IsNotFound(err) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("backup failed: %v", err)
+	}
+
+	if err := writer.Write(del.kind.Resource, obj); err != nil {
+		return fmt.Errorf("backup failed: %v", err)
+	}
+	return nil
+}
+
+// Object returns the current state of an item of the given ResourceType
+func (d *ResourceDetector) Object(resourceType string, item ResourceItem) (*unstructured.Unstructured, error) {
+	kind, ok := KindForResourceType(resourceType)
+	if !ok {
+		return nil, fmt.Errorf("unknown resource type %q", resourceType)
+	}
+	return d.getObject(context.Background(), kind, item)
+}
+
+// getObject gets an item through the dynamic client
+func (d *ResourceDetector) getObject(ctx context.Context, kind Kind, item ResourceItem) (*unstructured.Unstructured, error) {
+	if kind.Resource.Resource == "" {
+		return nil, fmt.Errorf("%s has no known API resource", kind.Name)
+	}
+	if d.clients.Dynamic == nil {
+		return nil, fmt.Errorf("no dynamic client available")
+	}
+	return d.clients.Dynamic.Resource(kind.Resource).Namespace(item.Namespace).Get(ctx, item.Name, metav1.GetOptions{})
+}
+
+// deleteItem deletes one item once the rate limiter allows it, backing off
+// as long as the API server asks it to
+func (d *ResourceDetector) deleteItem(ctx context.Context, limiter *rate.Limiter, del deletion, opts PruneOptions) error {
+	if !del.known {
+		return fmt.Errorf("unknown resource type %q", del.resourceType)
+	}
+
+	for attempt := 0; ; attempt++ {
+		if err := limiter.Wait(ctx); err != nil {
+			return err
+		}
 
-	return deletedCount, nil
+		err := del.kind.detector.Delete(ctx, d.clients, del.item, opts.deleteOptions(del.item))
+		if !apierrors.IsTooManyRequests(err) || attempt >= maxThrottleRetries {
+			return err
+		}
+
+		// Honor Retry-After, or back off exponentially without it
+		delay := time.Duration(1<<attempt) * time.Second
+		if seconds, ok := apierrors.SuggestsClientDelay(err); ok && seconds > 0 {
+			delay = time.Duration(seconds) * time.Second
+		}
+		time.Sleep(delay)
+	}
 }
@@ -0,0 +1,255 @@
+package resources
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Check is one condition an object must pass to be a candidate
+type Check struct {
+	Name string `json:"name"`
+	// Passed is true if the check allows pruning the object
+	Passed bool   `json:"passed"`
+	Detail string `json:"detail"`
+}
+
+// Explainer is implemented by detectors that can list the checks Find
+// applies to a single object. Detectors that don't are explained only by
+// the checks common to every type.
+type Explainer interface {
+	Explain(ctx context.Context, clients Clients, item ResourceItem) ([]Check, error)
+}
+
+// referenceExplainer is implemented by the built-in detectors that decide
+// from the reference index, so explain checks them against the same index
+// it lists the references from
+type referenceExplainer interface {
+	explainReferences(ctx context.Context, clients Clients, item ResourceItem, references ReferenceIndex) ([]Check, error)
+}
+
+// ExplainOptions holds the filters of the scan an object is explained for
+type ExplainOptions struct {
+	Windows       TimeWindows
+	LabelSelector string
+	// SweepCutoff, when set, requires the object to have been marked by
+	// then, like prune --sweep
+	SweepCutoff *time.Time
+}
+
+// Explanation says why an object is or isn't a candidate
+type Explanation struct {
+	ResourceType      string    `json:"resourceType"`
+	Namespace         string    `json:"namespace,omitempty"`
+	Name              string    `json:"name"`
+	CreationTimestamp time.Time `json:"creationTimestamp"`
+	Checks            []Check   `json:"checks"`
+
+	// References are the Pods, workload templates and ServiceAccounts
+	// pointing at the object. ReferencesTracked is false for types the
+	// reference index doesn't cover.
+	References        []Reference `json:"references,omitempty"`
+	ReferencesTracked bool        `json:"referencesTracked"`
+
+	// Candidate is the outcome of a full scan, set by Decide
+	Candidate bool   `json:"candidate"`
+	Reason    string `json:"reason,omitempty"`
+	Verdict   string `json:"verdict"`
+}
+
+// Explain evaluates every check that decides whether the object of kind
+// named name in namespace is a candidate, and collects the references to it
+func (d *ResourceDetector) Explain(kind Kind, namespace, name string, opts ExplainOptions) (*Explanation, error) {
+	ctx := context.Background()
+	if kind.Resource.Resource == "" {
+		return nil, fmt.Errorf("explain needs the API resource of %s, which its detector doesn't declare", kind.Name)
+	}
+	if d.clients.Dynamic == nil {
+		return nil, fmt.Errorf("no dynamic client available")
+	}
+
+	// Get the object
+	obj, err := d.clients.Dynamic.Resource(kind.Resource).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	item := NewResourceItem(obj)
+
+	explanation := &Explanation{
+		ResourceType:      kind.ResourceType,
+		Namespace:         item.Namespace,
+		Name:              item.Name,
+		CreationTimestamp: item.CreationTimestamp,
+	}
+
+	// References to it
+	var references ReferenceIndex
+	if _, ok := referenceKinds[kind.ResourceType]; ok {
+		references, err = BuildReferenceIndex(ctx, d.clients.Kube, namespace)
+		if err != nil {
+			return nil, fmt.Errorf("error finding references: %v", err)
+		}
+		explanation.References, explanation.ReferencesTracked = references.ReferencesTo(kind.ResourceType, item)
+	}
+
+	// The detector's own checks
+	if explainer, ok := kind.detector.(referenceExplainer); ok && references != nil {
+		checks, err := explainer.explainReferences(ctx, d.clients, item, references)
+		if err != nil {
+			return nil, err
+		}
+		explanation.Checks = checks
+	} else if explainer, ok := kind.detector.(Explainer); ok {
+		checks, err := explainer.Explain(ctx, d.clients, item)
+		if err != nil {
+			return nil, err
+		}
+		explanation.Checks = checks
+	} else {
+		explanation.Checks = []Check{{
+			Name:   "detector",
+			Passed: true,
+			Detail: fmt.Sprintf("the %s detector doesn't explain its checks; see the verdict", kind.Name),
+		}}
+	}
+
+	// Label selector
+	if opts.LabelSelector != "" {
+		selector, err := labels.Parse(opts.LabelSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid label selector: %v", err)
+		}
+		explanation.Checks = append(explanation.Checks, Check{
+			Name:   "labels",
+			Passed: selector.Matches(labels.Set(obj.GetLabels())),
+			Detail: "must match " + opts.LabelSelector,
+		})
+	}
+
+	// Creation time window
+	window := opts.Windows.For(kind.Name)
+	explanation.Checks = append(explanation.Checks, Check{
+		Name:   "created",
+		Passed: window.Contains(item.CreationTimestamp),
+		Detail: describeWindow(item.CreationTimestamp, window),
+	})
+
+	// Usage history
+	if d.history != nil && d.unusedSince != nil && tracksUsage(kind) {
+		explanation.Checks = append(explanation.Checks, Check{
+			Name:   "unused for",
+			Passed: d.history.UnusedSince(kind.ResourceType, item.Namespace+"/"+item.Name, *d.unusedSince),
+			Detail: "recorded scans must show it unreferenced since " + d.unusedSince.UTC().Format(time.RFC3339),
+		})
+	}
+
+	// Candidate mark
+	explanation.Checks = append(explanation.Checks, markCheck(item.CandidateSince, opts.SweepCutoff))
+
+	// --where expressions
+	if expressions := d.filters.For(kind.Name); len(expressions) > 0 {
+		scope := &filterScope{namespace: namespace}
+		if err := d.loadFilterScope(ctx, scope); err != nil {
+			return nil, err
+		}
+		vars, err := scope.variables(*obj)
+		if err != nil {
+			return nil, err
+		}
+		for _, expression := range expressions {
+			matched, err := expression.Matches(vars)
+			if err != nil {
+				return nil, err
+			}
+			explanation.Checks = append(explanation.Checks, Check{Name: "where", Passed: matched, Detail: expression.String()})
+		}
+	}
+
+	return explanation, nil
+}
+
+// Decide sets the verdict from candidates, the result of a scan with the
+// same options as the checks
+func (e *Explanation) Decide(candidates []ResourceList) {
+	for _, resourceList := range candidates {
+		if resourceList.ResourceType != e.ResourceType {
+			continue
+		}
+		for _, item := range resourceList.Items {
+			if item.Namespace == e.Namespace && item.Name == e.Name {
+				e.Candidate = true
+				e.Reason = item.Reason
+				e.Verdict = "would be pruned"
+				if item.Reason != "" {
+					e.Verdict += ": " + item.Reason
+				}
+				return
+			}
+		}
+	}
+
+	e.Candidate = false
+	e.Verdict = "kept"
+	for _, check := range e.Checks {
+		if !check.Passed {
+			e.Verdict += fmt.Sprintf(": %s check failed, %s", check.Name, check.Detail)
+			return
+		}
+	}
+	e.Verdict += ": the detector doesn't report it as unused"
+}
+
+// usageCheck passes if users, the objects referencing an object, is empty
+func usageCheck(unused string, users []string) Check {
+	if len(users) == 0 {
+		return Check{Name: "references", Passed: true, Detail: unused}
+	}
+	return Check{Name: "references", Passed: false, Detail: "referenced by " + strings.Join(users, ", ")}
+}
+
+// markCheck checks the candidate-since annotation against the --sweep cutoff
+func markCheck(since *time.Time, cutoff *time.Time) Check {
+	check := Check{Name: "marked", Passed: true}
+	switch {
+	case cutoff == nil && since == nil:
+		check.Detail = "not marked; only --sweep requires a mark"
+	case cutoff == nil:
+		check.Detail = fmt.Sprintf("marked since %s; only --sweep requires a mark", since.UTC().Format(time.RFC3339))
+	case since == nil:
+		check.Passed = false
+		check.Detail = fmt.Sprintf("not marked; --sweep requires the %s annotation", CandidateSinceAnnotation)
+	default:
+		check.Passed = !since.After(*cutoff)
+		check.Detail = fmt.Sprintf("marked since %s, must be marked since %s", since.UTC().Format(time.RFC3339), cutoff.UTC().Format(time.RFC3339))
+	}
+	return check
+}
+
+// describeWindow says when an object was created and which creation times
+// the window admits
+func describeWindow(created time.Time, w TimeWindow) string {
+	detail := "created " + created.UTC().Format(time.RFC3339)
+	switch {
+	case w.Before != nil && w.After != nil:
+		return detail + fmt.Sprintf(", must be created between %s and %s", w.After.UTC().Format(time.RFC3339), w.Before.UTC().Format(time.RFC3339))
+	case w.Before != nil:
+		return detail + ", must be created before " + w.Before.UTC().Format(time.RFC3339)
+	case w.After != nil:
+		return detail + ", must be created after " + w.After.UTC().Format(time.RFC3339)
+	}
+	return detail + ", no age limit"
+}
+
+// containsName reports whether names contains name
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
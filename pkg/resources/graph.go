@@ -0,0 +1,256 @@
+package resources
+
+import (
+	"context"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// GraphNode is an object in the reference graph
+type GraphNode struct {
+	ObjectRef
+	// Missing is true for objects that are referenced but don't exist
+	Missing bool `json:"missing,omitempty"`
+	// Candidate is true for objects a scan reports as unused
+	Candidate bool `json:"candidate,omitempty"`
+}
+
+// Graph holds the objects of a namespace and the references between them
+type Graph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []Reference `json:"edges"`
+}
+
+// graphKindOrder is the order nodes are listed in, workloads first
+var graphKindOrder = map[string]int{
+	"Service":               0,
+	"Deployment":            1,
+	"StatefulSet":           2,
+	"DaemonSet":             3,
+	"CronJob":               4,
+	"Pod":                   5,
+	"ServiceAccount":        6,
+	"ConfigMap":             7,
+	"Secret":                8,
+	"PersistentVolumeClaim": 9,
+}
+
+// graphCandidateKinds maps the ResourceTypes of candidates to the kind of their node
+var graphCandidateKinds = map[string]string{
+	"ConfigMaps":             "ConfigMap",
+	"Secrets":                "Secret",
+	"PersistentVolumeClaims": "PersistentVolumeClaim",
+	"Pods":                   "Pod",
+}
+
+// Graph builds the reference graph of namespace ("" for all): workloads,
+// Pods, ConfigMaps, Secrets, PVCs, ServiceAccounts and Services, with an
+// edge for each reference and for each Service selecting a Pod or workload
+func (d *ResourceDetector) Graph(namespace string) (*Graph, error) {
+	ctx := context.Background()
+	client := d.clients.Kube
+	nodes := make(map[ObjectRef]*GraphNode)
+	add := func(kind string, obj metav1.Object) {
+		ref := ObjectRef{Kind: kind, Namespace: obj.GetNamespace(), Name: obj.GetName()}
+		nodes[ref] = &GraphNode{ObjectRef: ref}
+	}
+
+	// Workloads and Pods, with the labels Services select them by
+	podLabels := make(map[ObjectRef]map[string]string)
+
+	pods, err := client.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range pods.Items {
+		add("Pod", &pods.Items[i])
+		podLabels[ObjectRef{Kind: "Pod", Namespace: pods.Items[i].Namespace, Name: pods.Items[i].Name}] = pods.Items[i].Labels
+	}
+
+	deployments, err := client.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range deployments.Items {
+		add("Deployment", &deployments.Items[i])
+		podLabels[ObjectRef{Kind: "Deployment", Namespace: deployments.Items[i].Namespace, Name: deployments.Items[i].Name}] = deployments.Items[i].Spec.Template.Labels
+	}
+
+	statefulSets, err := client.AppsV1().StatefulSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range statefulSets.Items {
+		add("StatefulSet", &statefulSets.Items[i])
+		podLabels[ObjectRef{Kind: "StatefulSet", Namespace: statefulSets.Items[i].Namespace, Name: statefulSets.Items[i].Name}] = statefulSets.Items[i].Spec.Template.Labels
+	}
+
+	daemonSets, err := client.AppsV1().DaemonSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range daemonSets.Items {
+		add("DaemonSet", &daemonSets.Items[i])
+		podLabels[ObjectRef{Kind: "DaemonSet", Namespace: daemonSets.Items[i].Namespace, Name: daemonSets.Items[i].Name}] = daemonSets.Items[i].Spec.Template.Labels
+	}
+
+	cronJobs, err := client.BatchV1().CronJobs(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range cronJobs.Items {
+		add("CronJob", &cronJobs.Items[i])
+	}
+
+	// Objects that are referenced
+	configMaps, err := client.CoreV1().ConfigMaps(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range configMaps.Items {
+		add("ConfigMap", &configMaps.Items[i])
+	}
+
+	secrets, err := client.CoreV1().Secrets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range secrets.Items {
+		add("Secret", &secrets.Items[i])
+	}
+
+	pvcs, err := client.CoreV1().PersistentVolumeClaims(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range pvcs.Items {
+		add("PersistentVolumeClaim", &pvcs.Items[i])
+	}
+
+	serviceAccounts, err := client.CoreV1().ServiceAccounts(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range serviceAccounts.Items {
+		add("ServiceAccount", &serviceAccounts.Items[i])
+	}
+
+	services, err := client.CoreV1().Services(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range services.Items {
+		add("Service", &services.Items[i])
+	}
+
+	// References from Pods, pod templates and ServiceAccounts
+	index, err := BuildReferenceIndex(ctx, client, namespace)
+	if err != nil {
+		return nil, err
+	}
+	var edges []Reference
+	seen := make(map[Reference]bool)
+	for _, refs := range index {
+		for _, ref := range refs {
+			if seen[ref] {
+				continue
+			}
+			seen[ref] = true
+			edges = append(edges, ref)
+
+			if _, ok := nodes[ref.To]; !ok {
+				nodes[ref.To] = &GraphNode{ObjectRef: ref.To, Missing: true}
+			}
+		}
+	}
+
+	// Services select Pods and the pods of workloads by label
+	for _, service := range services.Items {
+		edges = append(edges, serviceEdges(service, podLabels)...)
+	}
+
+	graph := &Graph{Nodes: []GraphNode{}, Edges: edges}
+	for _, node := range nodes {
+		graph.Nodes = append(graph.Nodes, *node)
+	}
+	graph.sort()
+	return graph, nil
+}
+
+// MarkCandidates flags the nodes of the objects in results. A node the graph
+// shows a reference to, e.g. one that started being used after the scan, is
+// never flagged; a Service selecting a finished Pod doesn't count.
+func (g *Graph) MarkCandidates(results []ResourceList) {
+	candidates := make(map[ObjectRef]bool)
+	for _, resourceList := range results {
+		kind, ok := graphCandidateKinds[resourceList.ResourceType]
+		if !ok {
+			continue
+		}
+		for _, item := range resourceList.Items {
+			candidates[ObjectRef{Kind: kind, Namespace: item.Namespace, Name: item.Name}] = true
+		}
+	}
+
+	referenced := make(map[ObjectRef]bool)
+	for _, edge := range g.Edges {
+		if edge.Via != "selector" {
+			referenced[edge.To] = true
+		}
+	}
+
+	for i := range g.Nodes {
+		ref := g.Nodes[i].ObjectRef
+		g.Nodes[i].Candidate = candidates[ref] && !referenced[ref]
+	}
+}
+
+// serviceEdges returns an edge from service to each Pod or workload in
+// podLabels its selector matches
+func serviceEdges(service corev1.Service, podLabels map[ObjectRef]map[string]string) []Reference {
+	// A Service without a selector has its endpoints managed by hand
+	if len(service.Spec.Selector) == 0 {
+		return nil
+	}
+	selector := labels.SelectorFromSet(service.Spec.Selector)
+	from := ObjectRef{Kind: "Service", Namespace: service.Namespace, Name: service.Name}
+
+	var edges []Reference
+	for target, podLabels := range podLabels {
+		if target.Namespace == service.Namespace && selector.Matches(labels.Set(podLabels)) {
+			edges = append(edges, Reference{From: from, To: target, Via: "selector"})
+		}
+	}
+	return edges
+}
+
+// sort orders nodes by kind, namespace and name, and edges by source and
+// target, for stable output
+func (g *Graph) sort() {
+	less := func(a, b ObjectRef) bool {
+		if a.Kind != b.Kind {
+			return graphKindOrder[a.Kind] < graphKindOrder[b.Kind]
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	}
+
+	sort.Slice(g.Nodes, func(i, j int) bool {
+		return less(g.Nodes[i].ObjectRef, g.Nodes[j].ObjectRef)
+	})
+	sort.Slice(g.Edges, func(i, j int) bool {
+		a, b := g.Edges[i], g.Edges[j]
+		if a.From != b.From {
+			return less(a.From, b.From)
+		}
+		if a.To != b.To {
+			return less(a.To, b.To)
+		}
+		return a.Via < b.Via
+	})
+}
@@ -0,0 +1,60 @@
+package resources
+
+import (
+	"time"
+
+	"github.com/manthan-parmar-1998/k8s-pruner/pkg/state"
+)
+
+// historyTypes are the types whose detectors record usage and honor --unused-for
+var historyTypes = map[string]bool{
+	"ConfigMaps":             true,
+	"Secrets":                true,
+	"PersistentVolumeClaims": true,
+}
+
+// UsageTracker is implemented by detectors that record usage with
+// FindOptions.RecordUsage and honor --unused-for, like the built-in
+// ConfigMap, Secret and PVC detectors
+type UsageTracker interface {
+	TracksUsage() bool
+}
+
+// tracksUsage reports whether the detector of kind records usage and honors --unused-for
+func tracksUsage(kind Kind) bool {
+	if tracker, ok := kind.detector.(UsageTracker); ok {
+		return tracker.TracksUsage()
+	}
+	return historyTypes[kind.ResourceType]
+}
+
+// UseHistory makes the detector record observed references in store. When
+// unusedSince is set, ConfigMaps, Secrets and PVCs are only reported if they
+// were unreferenced in every recorded scan since that time.
+func (d *ResourceDetector) UseHistory(store *state.Store, unusedSince *time.Time) {
+	d.history = store
+	d.unusedSince = unusedSince
+}
+
+// RecordUsage stores the references seen in this scan in the usage history.
+// used is keyed by "namespace/name".
+func (o FindOptions) RecordUsage(resourceType string, used map[string]bool) {
+	if o.History == nil {
+		return
+	}
+
+	now := time.Now()
+	o.History.RecordScan(resourceType, o.Namespace, now)
+	for key := range used {
+		o.History.MarkUsed(resourceType, key, now)
+	}
+}
+
+// UnusedLongEnough reports whether an object that is unreferenced right now
+// has also been unreferenced for the whole --unused-for window
+func (o FindOptions) UnusedLongEnough(resourceType, key string) bool {
+	if o.History == nil || o.UnusedSince == nil {
+		return true
+	}
+	return o.History.UnusedSince(resourceType, key, *o.UnusedSince)
+}
@@ -2,22 +2,32 @@ package resources
 
 import (
 	"context"
+	"fmt"
 	"time"
 
+	batchv1 "k8s.io/api/batch/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-// FindCompletedJobs finds completed jobs that are no longer needed
-func (d *ResourceDetector) FindCompletedJobs(namespace string, olderThan *time.Time, labelSelector string) (ResourceList, error) {
-	ctx := context.Background()
+// jobDetector finds completed jobs that are no longer needed
+type jobDetector struct{}
+
+// Name returns the canonical type name
+func (jobDetector) Name() string {
+	return "jobs"
+}
+
+// Find returns completed jobs that are no longer needed
+func (jobDetector) Find(ctx context.Context, clients Clients, opts FindOptions) (ResourceList, error) {
 	result := ResourceList{
 		ResourceType: "Jobs",
 		Items:        []ResourceItem{},
 	}
 
 	// Get all Jobs
-	jobs, err := d.client.BatchV1().Jobs(namespace).List(ctx, metav1.ListOptions{
-		LabelSelector: labelSelector,
+	jobs, err := clients.Kube.BatchV1().Jobs(opts.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: opts.LabelSelector,
 	})
 	if err != nil {
 		return result, err
@@ -31,28 +41,63 @@ func (d *ResourceDetector) FindCompletedJobs(namespace string, olderThan *time.T
 		}
 
 		// Skip jobs owned by CronJobs
-		isCronJobChild := false
-		for _, owner := range job.OwnerReferences {
-			if owner.Kind == "CronJob" {
-				isCronJobChild = true
-				break
-			}
-		}
-		if isCronJobChild {
+		if cronJobOwner(job) != "" {
 			continue
 		}
 
-		// Check age if filter is provided
-		if olderThan != nil && job.CreationTimestamp.Time.After(*olderThan) {
+		// Check the creation time window
+		if !opts.Window.Contains(job.CreationTimestamp.Time) {
 			continue
 		}
 
-		result.Items = append(result.Items, ResourceItem{
-			Name:      job.Name,
-			Namespace: job.Namespace,
-			Age:       job.CreationTimestamp.Time,
-		})
+		item := NewResourceItem(&job)
+		item.Reason = "completed and not owned by a CronJob"
+		item.Reasons = []string{ReasonJobCompleted, ReasonNoCronJob}
+		result.Items = append(result.Items, item)
 	}
 
 	return result, nil
 }
+
+// Explain checks a single Job the way Find does
+func (jobDetector) Explain(ctx context.Context, clients Clients, item ResourceItem) ([]Check, error) {
+	job, err := clients.Kube.BatchV1().Jobs(item.Namespace).Get(ctx, item.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	completed := Check{Name: "completed", Passed: job.Status.CompletionTime != nil, Detail: "not completed yet"}
+	if completed.Passed {
+		completed.Detail = "completed at " + job.Status.CompletionTime.UTC().Format(time.RFC3339)
+	}
+
+	owner := Check{Name: "owner", Passed: true, Detail: "not owned by a CronJob"}
+	if cronJob := cronJobOwner(*job); cronJob != "" {
+		owner.Passed = false
+		owner.Detail = fmt.Sprintf("owned by CronJob/%s, whose history limits clean it up", cronJob)
+	}
+
+	return []Check{completed, owner}, nil
+}
+
+// Delete deletes a single Job
+func (jobDetector) Delete(ctx context.Context, clients Clients, item ResourceItem, opts metav1.DeleteOptions) error {
+	return clients.Kube.BatchV1().Jobs(item.Namespace).Delete(ctx, item.Name, opts)
+}
+
+// RequiredPermissions returns the RBAC rules Find and Delete need
+func (jobDetector) RequiredPermissions() []rbacv1.PolicyRule {
+	return []rbacv1.PolicyRule{
+		{APIGroups: []string{"batch"}, Resources: []string{"jobs"}, Verbs: []string{"list", "delete"}},
+	}
+}
+
+// cronJobOwner returns the name of the CronJob owning job, or ""
+func cronJobOwner(job batchv1.Job) string {
+	for _, owner := range job.OwnerReferences {
+		if owner.Kind == "CronJob" {
+			return owner.Name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,199 @@
+package resources
+
+import (
+	"fmt"
+	"strings"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Kind describes a resource type the pruner can detect
+type Kind struct {
+	// Name is the canonical name used in --types, e.g. "configmaps"
+	Name string
+	// ResourceType is the name reported in ResourceList.ResourceType, e.g. "ConfigMaps"
+	ResourceType string
+	// Singular is the singular name, e.g. "configmap"
+	Singular string
+	// ShortNames are abbreviations such as "cm"
+	ShortNames []string
+	// Namespaced is false for cluster-scoped kinds
+	Namespaced bool
+	// Resource is the API resource, if known, for generic access through the dynamic client
+	Resource schema.GroupVersionResource
+
+	detector Detector
+}
+
+// kinds is the registry of all supported resource types, in registration order
+var kinds []Kind
+
+func init() {
+	mustRegister(configMapDetector{}, Kind{ResourceType: "ConfigMaps", Singular: "configmap", ShortNames: []string{"cm"}, Namespaced: true,
+		Resource: corev1.SchemeGroupVersion.WithResource("configmaps")})
+	mustRegister(secretDetector{}, Kind{ResourceType: "Secrets", Singular: "secret", Namespaced: true,
+		Resource: corev1.SchemeGroupVersion.WithResource("secrets")})
+	mustRegister(pvcDetector{}, Kind{ResourceType: "PersistentVolumeClaims", Singular: "persistentvolumeclaim", ShortNames: []string{"pvc"}, Namespaced: true,
+		Resource: corev1.SchemeGroupVersion.WithResource("persistentvolumeclaims")})
+	mustRegister(podDetector{}, Kind{ResourceType: "Pods", Singular: "pod", ShortNames: []string{"po"}, Namespaced: true,
+		Resource: corev1.SchemeGroupVersion.WithResource("pods")})
+	mustRegister(jobDetector{}, Kind{ResourceType: "Jobs", Singular: "job", Namespaced: true,
+		Resource: batchv1.SchemeGroupVersion.WithResource("jobs")})
+	mustRegister(namespaceDetector{}, Kind{ResourceType: "Namespaces", Singular: "namespace", ShortNames: []string{"ns"}, Namespaced: false,
+		Resource: corev1.SchemeGroupVersion.WithResource("namespaces")})
+}
+
+// Register adds a detector to the registry. kind.Name defaults to
+// detector.Name() and kind.ResourceType to kind.Name. Registering a name,
+// alias or ResourceType that is already taken fails.
+func Register(detector Detector, kind Kind) error {
+	if detector == nil {
+		return fmt.Errorf("cannot register a nil detector")
+	}
+	if kind.Name == "" {
+		kind.Name = detector.Name()
+	}
+	if kind.Name != detector.Name() {
+		return fmt.Errorf("kind name %q doesn't match detector name %q", kind.Name, detector.Name())
+	}
+	if kind.ResourceType == "" {
+		kind.ResourceType = kind.Name
+	}
+
+	for _, name := range kind.names() {
+		if existing, err := LookupKind(name); err == nil {
+			return fmt.Errorf("resource type %q is already registered by %s", name, existing.Name)
+		}
+	}
+	if existing, ok := KindForResourceType(kind.ResourceType); ok {
+		return fmt.Errorf("resource type %q is already registered by %s", kind.ResourceType, existing.Name)
+	}
+
+	kind.detector = detector
+	kinds = append(kinds, kind)
+	return nil
+}
+
+// mustRegister registers a built-in detector, panicking on conflicts
+func mustRegister(detector Detector, kind Kind) {
+	if err := Register(detector, kind); err != nil {
+		panic(err)
+	}
+}
+
+// Detector returns the detector registered for the kind
+func (k Kind) Detector() Detector {
+	return k.detector
+}
+
+// names returns every name the kind can be referred to by
+func (k Kind) names() []string {
+	var names []string
+	for _, name := range append([]string{k.Name, k.Singular, strings.ToLower(k.ResourceType)}, k.ShortNames...) {
+		if name != "" {
+			names = append(names, strings.ToLower(name))
+		}
+	}
+	return names
+}
+
+// KindNames returns the canonical names of all registered kinds
+func KindNames() []string {
+	names := make([]string, 0, len(kinds))
+	for _, kind := range kinds {
+		names = append(names, kind.Name)
+	}
+	return names
+}
+
+// LookupKind finds a kind by its plural, singular or short name,
+// ignoring case. Unknown names fail with a suggestion where possible.
+func LookupKind(name string) (Kind, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, kind := range kinds {
+		for _, candidate := range kind.names() {
+			if candidate == name {
+				return kind, nil
+			}
+		}
+	}
+
+	if suggestion := suggestKind(name); suggestion != "" {
+		return Kind{}, fmt.Errorf("unknown resource type %q, did you mean %q?", name, suggestion)
+	}
+	return Kind{}, fmt.Errorf("unknown resource type %q (supported: %s)", name, strings.Join(KindNames(), ", "))
+}
+
+// LookupKinds resolves a list of type names, dropping duplicates.
+// An empty list selects every registered kind.
+func LookupKinds(names []string) ([]Kind, error) {
+	if len(names) == 0 {
+		return append([]Kind(nil), kinds...), nil
+	}
+
+	var result []Kind
+	seen := make(map[string]bool)
+
+	for _, name := range names {
+		kind, err := LookupKind(name)
+		if err != nil {
+			return nil, err
+		}
+		if seen[kind.Name] {
+			continue
+		}
+		seen[kind.Name] = true
+		result = append(result, kind)
+	}
+
+	return result, nil
+}
+
+// KindForResourceType finds the kind that reports the given ResourceList.ResourceType
+func KindForResourceType(resourceType string) (Kind, bool) {
+	for _, kind := range kinds {
+		if kind.ResourceType == resourceType {
+			return kind, true
+		}
+	}
+	return Kind{}, false
+}
+
+// suggestKind returns the canonical name of the kind closest to name,
+// or "" if nothing is close enough to be a likely typo
+func suggestKind(name string) string {
+	best, bestDistance := "", 3
+	for _, kind := range kinds {
+		for _, candidate := range kind.names() {
+			if distance := editDistance(name, candidate); distance < bestDistance {
+				best, bestDistance = kind.Name, distance
+			}
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(b)]
+}
@@ -0,0 +1,105 @@
+package resources
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DeletionLimits caps how much a single prune may delete
+type DeletionLimits struct {
+	// MaxDeletions is the most items in total; 0 means no limit
+	MaxDeletions int
+	// MaxPercent is the most items as a percentage of the existing objects
+	// of each type, and of the pruned types in each namespace; 0 means no limit
+	MaxPercent float64
+}
+
+// CheckDeletionLimits returns a description of every limit results exceed,
+// with the counts that exceed it. Existing objects are counted in namespace,
+// or the whole cluster if it is empty, regardless of any other filter.
+func (d *ResourceDetector) CheckDeletionLimits(results []ResourceList, namespace string, limits DeletionLimits) ([]string, error) {
+	var violations []string
+
+	total := 0
+	for _, resourceList := range results {
+		total += len(resourceList.Items)
+	}
+	if limits.MaxDeletions > 0 && total > limits.MaxDeletions {
+		violations = append(violations, fmt.Sprintf("%d deletions exceed --max-deletions %d", total, limits.MaxDeletions))
+	}
+
+	if limits.MaxPercent <= 0 {
+		return violations, nil
+	}
+	if d.clients.Dynamic == nil {
+		return nil, fmt.Errorf("no dynamic client available")
+	}
+
+	ctx := context.Background()
+	deletionsByNamespace := make(map[string]int)
+	existingByNamespace := make(map[string]int)
+	for _, resourceList := range results {
+		if len(resourceList.Items) == 0 {
+			continue
+		}
+		kind, ok := KindForResourceType(resourceList.ResourceType)
+		if !ok || kind.Resource.Resource == "" {
+			return nil, fmt.Errorf("--max-deletions-percent can't count existing %s, whose detector doesn't declare its API resource", resourceList.ResourceType)
+		}
+
+		// Count every existing object of the type
+		objects, err := d.clients.Dynamic.Resource(kind.Resource).Namespace(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("error counting %s: %v", kind.Name, err)
+		}
+
+		if exceedsPercent(len(resourceList.Items), len(objects.Items), limits.MaxPercent) {
+			violations = append(violations, percentViolation(resourceList.ResourceType, len(resourceList.Items), len(objects.Items), limits.MaxPercent))
+		}
+
+		// Cluster-scoped kinds have no namespace to count against
+		if !kind.Namespaced {
+			continue
+		}
+		for _, obj := range objects.Items {
+			existingByNamespace[obj.GetNamespace()]++
+		}
+		for _, item := range resourceList.Items {
+			deletionsByNamespace[item.Namespace]++
+		}
+	}
+
+	namespaces := make([]string, 0, len(deletionsByNamespace))
+	for ns := range deletionsByNamespace {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Strings(namespaces)
+	for _, ns := range namespaces {
+		if exceedsPercent(deletionsByNamespace[ns], existingByNamespace[ns], limits.MaxPercent) {
+			violations = append(violations, percentViolation("namespace "+ns, deletionsByNamespace[ns], existingByNamespace[ns], limits.MaxPercent))
+		}
+	}
+
+	return violations, nil
+}
+
+// exceedsPercent reports whether deleting deletions of existing objects is
+// more than maxPercent of them
+func exceedsPercent(deletions, existing int, maxPercent float64) bool {
+	if existing == 0 {
+		return deletions > 0
+	}
+	return float64(deletions)*100/float64(existing) > maxPercent
+}
+
+// percentViolation describes an exceeded --max-deletions-percent
+func percentViolation(scope string, deletions, existing int, maxPercent float64) string {
+	percent := 100.0
+	if existing > 0 {
+		percent = float64(deletions) * 100 / float64(existing)
+	}
+	return fmt.Sprintf("%s: %d of %d existing (%.1f%%) exceeds --max-deletions-percent %g", scope, deletions, existing, percent, maxPercent)
+}
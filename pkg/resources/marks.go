@@ -0,0 +1,233 @@
+package resources
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// CandidateSinceAnnotation records when mark first saw an object unused
+	CandidateSinceAnnotation = "k8s-pruner.io/candidate-since"
+	// CandidateLabel is optionally set alongside the annotation so marked
+	// objects can be selected, e.g. by dashboards
+	CandidateLabel = "k8s-pruner.io/candidate"
+)
+
+// candidateSince returns the time in an object's candidate-since
+// annotation, or nil if it isn't marked
+func candidateSince(annotations map[string]string) *time.Time {
+	value, ok := annotations[CandidateSinceAnnotation]
+	if !ok {
+		return nil
+	}
+	since, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil
+	}
+	return &since
+}
+
+// MarkOptions controls MarkCandidates
+type MarkOptions struct {
+	// Label also sets CandidateLabel on marked objects
+	Label bool
+	// DryRun reports the changes without making them
+	DryRun bool
+	// ServerDryRun sends every patch as a server-side dry run
+	ServerDryRun bool
+}
+
+// MarkResult is the outcome of marking or unmarking one object
+type MarkResult struct {
+	ResourceType string `json:"resourceType"`
+	Name         string `json:"name"`
+	Namespace    string `json:"namespace"`
+	// Action is "marked" or "cleared"
+	Action string `json:"action"`
+	Error  string `json:"error,omitempty"`
+}
+
+// MarkReport lists every object MarkCandidates changed
+type MarkReport struct {
+	Results []MarkResult `json:"results"`
+}
+
+// Failed returns the number of objects that could not be changed
+func (r MarkReport) Failed() int {
+	failed := 0
+	for _, result := range r.Results {
+		if result.Error != "" {
+			failed++
+		}
+	}
+	return failed
+}
+
+// MarkCandidates annotates every item in results that isn't marked yet with
+// the time it was first seen unused, and clears the marks of objects of the
+// same types in scope that are in use again. Objects already marked keep
+// their original time, and so do marked objects that are still unused but
+// left out of results by the creation window, --where or usage history.
+func (d *ResourceDetector) MarkCandidates(results []ResourceList, namespace string, types []string, labelSelector string, at time.Time, opts MarkOptions) (MarkReport, error) {
+	report := MarkReport{Results: []MarkResult{}}
+	ctx := context.Background()
+
+	selectedKinds, err := LookupKinds(types)
+	if err != nil {
+		return report, err
+	}
+	if d.clients.Dynamic == nil {
+		return report, fmt.Errorf("no dynamic client available")
+	}
+
+	// Index the candidates by type and namespace/name
+	candidates := make(map[string]map[string]ResourceItem)
+	for _, resourceList := range results {
+		items := make(map[string]ResourceItem)
+		for _, item := range resourceList.Items {
+			items[item.Namespace+"/"+item.Name] = item
+		}
+		candidates[resourceList.ResourceType] = items
+	}
+
+	for _, kind := range selectedKinds {
+		// Cluster-scoped kinds don't apply when a namespace is targeted
+		if !kind.Namespaced && namespace != "" {
+			continue
+		}
+		if kind.Resource.Resource == "" {
+			if len(candidates[kind.ResourceType]) > 0 {
+				return report, fmt.Errorf("%s: marking needs the API resource, which its detector doesn't declare", kind.Name)
+			}
+			continue
+		}
+
+		// Get the objects in scope to see which are marked already
+		objects, err := d.clients.Dynamic.Resource(kind.Resource).Namespace(namespace).List(ctx, metav1.ListOptions{
+			LabelSelector: labelSelector,
+		})
+		if err != nil {
+			return report, fmt.Errorf("%s: %v", kind.Name, err)
+		}
+
+		// What the detector finds unused without any filters, listed the
+		// first time a mark may need clearing
+		var unused map[string]bool
+
+		for _, obj := range objects.Items {
+			key := obj.GetNamespace() + "/" + obj.GetName()
+			item, isCandidate := candidates[kind.ResourceType][key]
+			marked := candidateSince(obj.GetAnnotations()) != nil
+			_, labeled := obj.GetLabels()[CandidateLabel]
+
+			var patch map[string]interface{}
+			var action string
+			switch {
+			case isCandidate && item.UID != "" && item.UID != obj.GetUID():
+				// Recreated since it was found; leave it for the next run
+				continue
+			case isCandidate && (!marked || (opts.Label && !labeled)):
+				metadata := map[string]interface{}{}
+				if !marked {
+					metadata["annotations"] = map[string]interface{}{CandidateSinceAnnotation: at.UTC().Format(time.RFC3339)}
+				}
+				if opts.Label {
+					metadata["labels"] = map[string]interface{}{CandidateLabel: "true"}
+				}
+				patch = map[string]interface{}{"metadata": metadata}
+				action = "marked"
+			case !isCandidate && (marked || labeled):
+				if unused == nil {
+					unused, err = d.unfiltered(ctx, kind, namespace, labelSelector)
+					if err != nil {
+						return report, fmt.Errorf("%s: %v", kind.Name, err)
+					}
+				}
+				if unused[key] {
+					// Only filtered out, not in use
+					continue
+				}
+
+				// Used again, so the owner no longer needs to be warned
+				patch = map[string]interface{}{"metadata": map[string]interface{}{
+					"annotations": map[string]interface{}{CandidateSinceAnnotation: nil},
+					"labels":      map[string]interface{}{CandidateLabel: nil},
+				}}
+				action = "cleared"
+			default:
+				continue
+			}
+
+			result := MarkResult{
+				ResourceType: kind.ResourceType,
+				Name:         obj.GetName(),
+				Namespace:    obj.GetNamespace(),
+				Action:       action,
+			}
+			if !opts.DryRun {
+				if err := d.patchMetadata(ctx, kind, obj.GetNamespace(), obj.GetName(), patch, opts.ServerDryRun); err != nil {
+					result.Error = err.Error()
+				}
+			}
+			report.Results = append(report.Results, result)
+		}
+	}
+
+	return report, nil
+}
+
+// unfiltered returns the namespace/name keys of the objects of kind the
+// detector finds unused regardless of their age, --where and usage history
+func (d *ResourceDetector) unfiltered(ctx context.Context, kind Kind, namespace, labelSelector string) (map[string]bool, error) {
+	resourceList, err := kind.detector.Find(ctx, d.clients, FindOptions{
+		Namespace:     namespace,
+		LabelSelector: labelSelector,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	unused := make(map[string]bool)
+	for _, item := range resourceList.Items {
+		unused[item.Namespace+"/"+item.Name] = true
+	}
+	return unused, nil
+}
+
+// patchMetadata applies a JSON merge patch to one object
+func (d *ResourceDetector) patchMetadata(ctx context.Context, kind Kind, namespace, name string, patch map[string]interface{}, dryRun bool) error {
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return err
+	}
+
+	opts := metav1.PatchOptions{}
+	if dryRun {
+		opts.DryRun = []string{metav1.DryRunAll}
+	}
+	_, err = d.clients.Dynamic.Resource(kind.Resource).Namespace(namespace).Patch(ctx, name, types.MergePatchType, data, opts)
+	return err
+}
+
+// MarkedBefore returns the items of results that were marked as candidates
+// at or before cutoff. Unmarked items are dropped.
+func MarkedBefore(results []ResourceList, cutoff time.Time) []ResourceList {
+	var marked []ResourceList
+	for _, resourceList := range results {
+		kept := ResourceList{ResourceType: resourceList.ResourceType, Items: []ResourceItem{}}
+		for _, item := range resourceList.Items {
+			if item.CandidateSince != nil && !item.CandidateSince.After(cutoff) {
+				kept.Items = append(kept.Items, item)
+			}
+		}
+		if len(kept.Items) > 0 {
+			marked = append(marked, kept)
+		}
+	}
+	return marked
+}
@@ -3,23 +3,31 @@ package resources
 
 import (
 	"context"
-	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
 )
 
-// FindUnusedNamespaces finds namespaces that don't contain any resources
-func (d *ResourceDetector) FindUnusedNamespaces(olderThan *time.Time, labelSelector string) (ResourceList, error) {
-	ctx := context.Background()
+// namespaceDetector finds namespaces that don't contain any resources
+type namespaceDetector struct{}
+
+// Name returns the canonical type name
+func (namespaceDetector) Name() string {
+	return "namespaces"
+}
+
+// Find returns namespaces that don't contain any resources
+func (namespaceDetector) Find(ctx context.Context, clients Clients, opts FindOptions) (ResourceList, error) {
 	result := ResourceList{
 		ResourceType: "Namespaces",
 		Items:        []ResourceItem{},
 	}
 
 	// Get all namespaces
-	namespaces, err := d.client.CoreV1().Namespaces().List(ctx, metav1.ListOptions{
-		LabelSelector: labelSelector,
+	namespaces, err := clients.Kube.CoreV1().Namespaces().List(ctx, metav1.ListOptions{
+		LabelSelector: opts.LabelSelector,
 	})
 	if err != nil {
 		return result, err
@@ -32,35 +40,66 @@ func (d *ResourceDetector) FindUnusedNamespaces(olderThan *time.Time, labelSelec
 			continue
 		}
 
-		// Check age if filter is provided
-		if olderThan != nil && ns.CreationTimestamp.Time.After(*olderThan) {
+		// Check the creation time window
+		if !opts.Window.Contains(ns.CreationTimestamp.Time) {
 			continue
 		}
 
 		// Check if namespace has any resources
-		isEmpty, err := d.isNamespaceEmpty(ns.Name)
+		isEmpty, err := isNamespaceEmpty(ctx, clients.Kube, ns.Name)
 		if err != nil {
 			return result, err
 		}
 
 		if isEmpty {
-			result.Items = append(result.Items, ResourceItem{
-				Name:      ns.Name,
-				Namespace: "",
-				Age:       ns.CreationTimestamp.Time,
-			})
+			item := NewResourceItem(&ns)
+			item.Reason = "contains no workloads, services, ConfigMaps or Secrets"
+			item.Reasons = []string{ReasonEmptyNamespace}
+			result.Items = append(result.Items, item)
 		}
 	}
 
 	return result, nil
 }
 
-// isNamespaceEmpty checks if a namespace has any resources
-func (d *ResourceDetector) isNamespaceEmpty(namespace string) (bool, error) {
-	ctx := context.Background()
+// Explain checks a single namespace the way Find does
+func (namespaceDetector) Explain(ctx context.Context, clients Clients, item ResourceItem) ([]Check, error) {
+	checks := []Check{{
+		Name:   "system",
+		Passed: !isSystemNamespace(item.Name),
+		Detail: "system and default namespaces are never pruned",
+	}}
+
+	isEmpty, err := isNamespaceEmpty(ctx, clients.Kube, item.Name)
+	if err != nil {
+		return nil, err
+	}
+	empty := Check{Name: "empty", Passed: isEmpty, Detail: "contains no workloads, services, ConfigMaps or Secrets"}
+	if !isEmpty {
+		empty.Detail = "contains workloads, services, ConfigMaps or Secrets"
+	}
 
+	return append(checks, empty), nil
+}
+
+// Delete deletes a single namespace
+func (namespaceDetector) Delete(ctx context.Context, clients Clients, item ResourceItem, opts metav1.DeleteOptions) error {
+	return clients.Kube.CoreV1().Namespaces().Delete(ctx, item.Name, opts)
+}
+
+// RequiredPermissions returns the RBAC rules Find and Delete need
+func (namespaceDetector) RequiredPermissions() []rbacv1.PolicyRule {
+	return []rbacv1.PolicyRule{
+		{APIGroups: []string{""}, Resources: []string{"namespaces"}, Verbs: []string{"list", "delete"}},
+		{APIGroups: []string{""}, Resources: []string{"pods", "services", "configmaps", "secrets"}, Verbs: []string{"list"}},
+		{APIGroups: []string{"apps"}, Resources: []string{"deployments", "statefulsets", "daemonsets"}, Verbs: []string{"list"}},
+	}
+}
+
+// isNamespaceEmpty checks if a namespace has any resources
+func isNamespaceEmpty(ctx context.Context, client kubernetes.Interface, namespace string) (bool, error) {
 	// Check for pods
-	pods, err := d.client.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	pods, err := client.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
 	if err != nil {
 		return false, err
 	}
@@ -69,7 +108,7 @@ func (d *ResourceDetector) isNamespaceEmpty(namespace string) (bool, error) {
 	}
 
 	// Check for services
-	services, err := d.client.CoreV1().Services(namespace).List(ctx, metav1.ListOptions{})
+	services, err := client.CoreV1().Services(namespace).List(ctx, metav1.ListOptions{})
 	if err != nil {
 		return false, err
 	}
@@ -79,7 +118,7 @@ func (d *ResourceDetector) isNamespaceEmpty(namespace string) (bool, error) {
 	}
 
 	// Check for deployments
-	deployments, err := d.client.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{})
+	deployments, err := client.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{})
 	if err != nil {
 		return false, err
 	}
@@ -88,7 +127,7 @@ func (d *ResourceDetector) isNamespaceEmpty(namespace string) (bool, error) {
 	}
 
 	// Check for statefulsets
-	statefulsets, err := d.client.AppsV1().StatefulSets(namespace).List(ctx, metav1.ListOptions{})
+	statefulsets, err := client.AppsV1().StatefulSets(namespace).List(ctx, metav1.ListOptions{})
 	if err != nil {
 		return false, err
 	}
@@ -97,7 +136,7 @@ func (d *ResourceDetector) isNamespaceEmpty(namespace string) (bool, error) {
 	}
 
 	// Check for daemonsets
-	daemonsets, err := d.client.AppsV1().DaemonSets(namespace).List(ctx, metav1.ListOptions{})
+	daemonsets, err := client.AppsV1().DaemonSets(namespace).List(ctx, metav1.ListOptions{})
 	if err != nil {
 		return false, err
 	}
@@ -106,7 +145,7 @@ func (d *ResourceDetector) isNamespaceEmpty(namespace string) (bool, error) {
 	}
 
 	// Check for configmaps (excluding default ones)
-	configmaps, err := d.client.CoreV1().ConfigMaps(namespace).List(ctx, metav1.ListOptions{})
+	configmaps, err := client.CoreV1().ConfigMaps(namespace).List(ctx, metav1.ListOptions{})
 	if err != nil {
 		return false, err
 	}
@@ -117,7 +156,7 @@ func (d *ResourceDetector) isNamespaceEmpty(namespace string) (bool, error) {
 	}
 
 	// Check for secrets (excluding default ones)
-	secrets, err := d.client.CoreV1().Secrets(namespace).List(ctx, metav1.ListOptions{})
+	secrets, err := client.CoreV1().Secrets(namespace).List(ctx, metav1.ListOptions{})
 	if err != nil {
 		return false, err
 	}
@@ -2,23 +2,32 @@ package resources
 
 import (
 	"context"
-	"time"
+	"fmt"
+	"strings"
 
 	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-// FindCompletedPods finds completed pods that are no longer needed
-func (d *ResourceDetector) FindCompletedPods(namespace string, olderThan *time.Time, labelSelector string) (ResourceList, error) {
-	ctx := context.Background()
+// podDetector finds completed pods that are no longer needed
+type podDetector struct{}
+
+// Name returns the canonical type name
+func (podDetector) Name() string {
+	return "pods"
+}
+
+// Find returns completed pods that are no longer needed
+func (podDetector) Find(ctx context.Context, clients Clients, opts FindOptions) (ResourceList, error) {
 	result := ResourceList{
 		ResourceType: "Pods",
 		Items:        []ResourceItem{},
 	}
 
 	// Get all Pods
-	pods, err := d.client.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
-		LabelSelector: labelSelector,
+	pods, err := clients.Kube.CoreV1().Pods(opts.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: opts.LabelSelector,
 	})
 	if err != nil {
 		return result, err
@@ -37,17 +46,56 @@ func (d *ResourceDetector) FindCompletedPods(namespace string, olderThan *time.T
 			continue
 		}
 
-		// Check age if filter is provided
-		if olderThan != nil && pod.CreationTimestamp.Time.After(*olderThan) {
+		// Check the creation time window
+		if !opts.Window.Contains(pod.CreationTimestamp.Time) {
 			continue
 		}
 
-		result.Items = append(result.Items, ResourceItem{
-			Name:      pod.Name,
-			Namespace: pod.Namespace,
-			Age:       pod.CreationTimestamp.Time,
-		})
+		item := NewResourceItem(&pod)
+		item.Reason = fmt.Sprintf("%s and not owned by a controller", strings.ToLower(string(pod.Status.Phase)))
+		item.Reasons = []string{ReasonPodSucceeded, ReasonNoController}
+		if pod.Status.Phase == corev1.PodFailed {
+			item.Reasons[0] = ReasonPodFailed
+		}
+		result.Items = append(result.Items, item)
 	}
 
 	return result, nil
 }
+
+// Explain checks a single Pod the way Find does
+func (podDetector) Explain(ctx context.Context, clients Clients, item ResourceItem) ([]Check, error) {
+	pod, err := clients.Kube.CoreV1().Pods(item.Namespace).Get(ctx, item.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	checks := []Check{{
+		Name:   "phase",
+		Passed: pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed,
+		Detail: fmt.Sprintf("phase is %s; only Succeeded and Failed pods are pruned", pod.Status.Phase),
+	}}
+
+	owner := Check{Name: "owner", Passed: len(pod.OwnerReferences) == 0, Detail: "not owned by a controller"}
+	if !owner.Passed {
+		var owners []string
+		for _, ref := range pod.OwnerReferences {
+			owners = append(owners, ref.Kind+"/"+ref.Name)
+		}
+		owner.Detail = fmt.Sprintf("owned by %s, which cleans it up", strings.Join(owners, ", "))
+	}
+
+	return append(checks, owner), nil
+}
+
+// Delete deletes a single Pod
+func (podDetector) Delete(ctx context.Context, clients Clients, item ResourceItem, opts metav1.DeleteOptions) error {
+	return clients.Kube.CoreV1().Pods(item.Namespace).Delete(ctx, item.Name, opts)
+}
+
+// RequiredPermissions returns the RBAC rules Find and Delete need
+func (podDetector) RequiredPermissions() []rbacv1.PolicyRule {
+	return []rbacv1.PolicyRule{
+		{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"list", "delete"}},
+	}
+}
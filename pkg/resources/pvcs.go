@@ -2,45 +2,43 @@ package resources
 
 import (
 	"context"
-	"time"
 
+	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-// FindUnusedPVCs finds PVCs that are not mounted in any Pod
-func (d *ResourceDetector) FindUnusedPVCs(namespace string, olderThan *time.Time, labelSelector string) (ResourceList, error) {
-	ctx := context.Background()
+// pvcDetector finds PVCs that no Pod or workload mounts
+type pvcDetector struct{}
+
+// Name returns the canonical type name
+func (pvcDetector) Name() string {
+	return "pvcs"
+}
+
+// Find returns PVCs that no Pod or workload mounts
+func (pvcDetector) Find(ctx context.Context, clients Clients, opts FindOptions) (ResourceList, error) {
 	result := ResourceList{
 		ResourceType: "PersistentVolumeClaims",
 		Items:        []ResourceItem{},
 	}
 
 	// Get all PVCs
-	pvcs, err := d.client.CoreV1().PersistentVolumeClaims(namespace).List(ctx, metav1.ListOptions{
-		LabelSelector: labelSelector,
+	pvcs, err := clients.Kube.CoreV1().PersistentVolumeClaims(opts.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: opts.LabelSelector,
 	})
 	if err != nil {
 		return result, err
 	}
 
-	// Get all Pods
-	pods, err := d.client.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	// Track which PVCs Pods and workload templates mount
+	references, err := BuildReferenceIndex(ctx, clients.Kube, opts.Namespace)
 	if err != nil {
 		return result, err
 	}
+	usedPVCs := references.referenced("PersistentVolumeClaim")
 
-	// Track which PVCs are in use
-	usedPVCs := make(map[string]bool)
-
-	// Check PVC usage in Pods
-	for _, pod := range pods.Items {
-		for _, volume := range pod.Spec.Volumes {
-			if volume.PersistentVolumeClaim != nil {
-				key := pod.Namespace + "/" + volume.PersistentVolumeClaim.ClaimName
-				usedPVCs[key] = true
-			}
-		}
-	}
+	// Remember what is in use for future --unused-for checks
+	opts.RecordUsage("PersistentVolumeClaims", usedPVCs)
 
 	// Add unused PVCs to result
 	for _, pvc := range pvcs.Items {
@@ -48,18 +46,51 @@ func (d *ResourceDetector) FindUnusedPVCs(namespace string, olderThan *time.Time
 
 		// Check if PVC is unused
 		if !usedPVCs[key] {
-			// Check age if filter is provided
-			if olderThan != nil && pvc.CreationTimestamp.Time.After(*olderThan) {
+			// Check the creation time window
+			if !opts.Window.Contains(pvc.CreationTimestamp.Time) {
 				continue
 			}
 
-			result.Items = append(result.Items, ResourceItem{
-				Name:      pvc.Name,
-				Namespace: pvc.Namespace,
-				Age:       pvc.CreationTimestamp.Time,
-			})
+			// Check that it also stayed unused for the --unused-for window
+			if !opts.UnusedLongEnough("PersistentVolumeClaims", key) {
+				continue
+			}
+
+			item := NewResourceItem(&pvc)
+			item.Reason = "not mounted by any pod or workload"
+			item.Reasons = []string{ReasonUnmounted}
+			result.Items = append(result.Items, item)
 		}
 	}
 
 	return result, nil
 }
+
+// Explain checks a single PVC the way Find does
+func (d pvcDetector) Explain(ctx context.Context, clients Clients, item ResourceItem) ([]Check, error) {
+	references, err := BuildReferenceIndex(ctx, clients.Kube, item.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	return d.explainReferences(ctx, clients, item, references)
+}
+
+// explainReferences checks a single PVC against references
+func (pvcDetector) explainReferences(ctx context.Context, clients Clients, item ResourceItem, references ReferenceIndex) ([]Check, error) {
+	// Find the Pods and workloads mounting it
+	users := references.ReferencedBy(ObjectRef{Kind: "PersistentVolumeClaim", Namespace: item.Namespace, Name: item.Name})
+
+	return []Check{usageCheck("not mounted by any pod or workload", users)}, nil
+}
+
+// Delete deletes a single PVC
+func (pvcDetector) Delete(ctx context.Context, clients Clients, item ResourceItem, opts metav1.DeleteOptions) error {
+	return clients.Kube.CoreV1().PersistentVolumeClaims(item.Namespace).Delete(ctx, item.Name, opts)
+}
+
+// RequiredPermissions returns the RBAC rules Find and Delete need
+func (pvcDetector) RequiredPermissions() []rbacv1.PolicyRule {
+	return append([]rbacv1.PolicyRule{
+		{APIGroups: []string{""}, Resources: []string{"persistentvolumeclaims"}, Verbs: []string{"list", "delete"}},
+	}, referencePermissions()...)
+}
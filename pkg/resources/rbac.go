@@ -0,0 +1,99 @@
+package resources
+
+import (
+	"sort"
+	"strings"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+)
+
+// RequiredPermissions returns the RBAC rules every command except restore
+// needs for the given types. No types means every registered kind. On top
+// of what each detector needs to find and delete, the types need get for
+// backups, verification and explain, list for --where and
+// --max-deletions-percent, and patch for mark. Rules for the same API group
+// and verbs are merged.
+func RequiredPermissions(types []string) ([]rbacv1.PolicyRule, error) {
+	selectedKinds, err := LookupKinds(types)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := []rbacv1.PolicyRule{
+		// The cluster is identified by its kube-system namespace, and
+		// --where reads namespace labels
+		{APIGroups: []string{""}, Resources: []string{"namespaces"}, Verbs: []string{"get", "list"}},
+		// graph and dangling list what can be referenced, and graph Services
+		{APIGroups: []string{""}, Resources: []string{"configmaps", "secrets", "persistentvolumeclaims", "services"}, Verbs: []string{"list"}},
+	}
+	rules = append(rules, referencePermissions()...)
+
+	for _, kind := range selectedKinds {
+		rules = append(rules, kind.detector.RequiredPermissions()...)
+		if kind.Resource.Resource != "" {
+			rules = append(rules, rbacv1.PolicyRule{
+				APIGroups: []string{kind.Resource.Group},
+				Resources: []string{kind.Resource.Resource},
+				Verbs:     []string{"get", "list", "patch"},
+			})
+		}
+	}
+
+	return mergeRules(rules), nil
+}
+
+// mergeRules combines rules into one per API group and set of verbs,
+// sorted for stable output
+func mergeRules(rules []rbacv1.PolicyRule) []rbacv1.PolicyRule {
+	// Collect the verbs of each group and resource
+	verbs := make(map[string]map[string]map[string]bool)
+	for _, rule := range rules {
+		for _, group := range rule.APIGroups {
+			if verbs[group] == nil {
+				verbs[group] = make(map[string]map[string]bool)
+			}
+			for _, resource := range rule.Resources {
+				if verbs[group][resource] == nil {
+					verbs[group][resource] = make(map[string]bool)
+				}
+				for _, verb := range rule.Verbs {
+					verbs[group][resource][verb] = true
+				}
+			}
+		}
+	}
+
+	// Group the resources with the same verbs
+	var merged []rbacv1.PolicyRule
+	byKey := make(map[string]int)
+	for group, resources := range verbs {
+		for resource, set := range resources {
+			var list []string
+			for verb := range set {
+				list = append(list, verb)
+			}
+			sort.Strings(list)
+
+			key := group + "|" + strings.Join(list, ",")
+			i, ok := byKey[key]
+			if !ok {
+				i = len(merged)
+				byKey[key] = i
+				merged = append(merged, rbacv1.PolicyRule{APIGroups: []string{group}, Verbs: list})
+			}
+			merged[i].Resources = append(merged[i].Resources, resource)
+		}
+	}
+
+	for i := range merged {
+		sort.Strings(merged[i].Resources)
+	}
+	sort.Slice(merged, func(i, j int) bool {
+		a, b := merged[i], merged[j]
+		if a.APIGroups[0] != b.APIGroups[0] {
+			return a.APIGroups[0] < b.APIGroups[0]
+		}
+		return a.Resources[0] < b.Resources[0]
+	})
+	return merged
+}
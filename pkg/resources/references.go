@@ -0,0 +1,239 @@
+package resources
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ObjectRef identifies an object by API kind, namespace and name
+type ObjectRef struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+}
+
+// String formats the reference as "Kind/namespace/name", or "Kind/name" for
+// cluster-scoped objects
+func (r ObjectRef) String() string {
+	if r.Namespace == "" {
+		return r.Kind + "/" + r.Name
+	}
+	return r.Kind + "/" + r.Namespace + "/" + r.Name
+}
+
+// Reference is a pointer from one object to another, e.g. a Pod mounting a
+// ConfigMap as a volume
+type Reference struct {
+	From ObjectRef `json:"from"`
+	To   ObjectRef `json:"to"`
+	// Via says how the reference is made: volume, env, envFrom,
+	// imagePullSecret or serviceAccount
+	Via string `json:"via"`
+	// Optional is true if From tolerates To being missing
+	Optional bool `json:"optional,omitempty"`
+}
+
+// ReferenceIndex holds the references found in a namespace, keyed by target
+type ReferenceIndex map[ObjectRef][]Reference
+
+// ReferencedBy returns the objects referencing target, formatted with ObjectRef.String
+func (idx ReferenceIndex) ReferencedBy(target ObjectRef) []string {
+	var referrers []string
+	seen := make(map[ObjectRef]bool)
+	for _, ref := range idx[target] {
+		if !seen[ref.From] {
+			seen[ref.From] = true
+			referrers = append(referrers, ref.From.String())
+		}
+	}
+	return referrers
+}
+
+// referenced returns the namespace/name keys of the objects of kind that
+// something references
+func (idx ReferenceIndex) referenced(kind string) map[string]bool {
+	used := make(map[string]bool)
+	for target := range idx {
+		if target.Kind == kind {
+			used[target.Namespace+"/"+target.Name] = true
+		}
+	}
+	return used
+}
+
+// referenceKinds maps the ResourceTypes that references point to to their API kind
+var referenceKinds = map[string]string{
+	"ConfigMaps":             "ConfigMap",
+	"Secrets":                "Secret",
+	"PersistentVolumeClaims": "PersistentVolumeClaim",
+	"ServiceAccounts":        "ServiceAccount",
+}
+
+// ReferencesTo returns the references to an item of the given ResourceType.
+// ok is false for types the index doesn't track references to.
+func (idx ReferenceIndex) ReferencesTo(resourceType string, item ResourceItem) (refs []Reference, ok bool) {
+	kind, ok := referenceKinds[resourceType]
+	if !ok {
+		return nil, false
+	}
+	return idx[ObjectRef{Kind: kind, Namespace: item.Namespace, Name: item.Name}], true
+}
+
+// References builds the reference index of namespace ("" for all)
+func (d *ResourceDetector) References(namespace string) (ReferenceIndex, error) {
+	return BuildReferenceIndex(context.Background(), d.clients.Kube, namespace)
+}
+
+// BuildReferenceIndex collects the references made by Pods, the pod
+// templates of workloads and ServiceAccounts in namespace ("" for all). It
+// is the one source of references: the detectors, explain, graph, dangling
+// and --where all use it.
+func BuildReferenceIndex(ctx context.Context, client kubernetes.Interface, namespace string) (ReferenceIndex, error) {
+	var refs []Reference
+
+	// Pods
+	pods, err := client.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, pod := range pods.Items {
+		refs = append(refs, podSpecReferences(ObjectRef{Kind: "Pod", Namespace: pod.Namespace, Name: pod.Name}, pod.Spec)...)
+	}
+
+	// Pod templates of workloads, which keep their references even when scaled to zero
+	deployments, err := client.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range deployments.Items {
+		refs = append(refs, podSpecReferences(ObjectRef{Kind: "Deployment", Namespace: w.Namespace, Name: w.Name}, w.Spec.Template.Spec)...)
+	}
+
+	statefulSets, err := client.AppsV1().StatefulSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range statefulSets.Items {
+		refs = append(refs, podSpecReferences(ObjectRef{Kind: "StatefulSet", Namespace: w.Namespace, Name: w.Name}, w.Spec.Template.Spec)...)
+	}
+
+	daemonSets, err := client.AppsV1().DaemonSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range daemonSets.Items {
+		refs = append(refs, podSpecReferences(ObjectRef{Kind: "DaemonSet", Namespace: w.Namespace, Name: w.Name}, w.Spec.Template.Spec)...)
+	}
+
+	cronJobs, err := client.BatchV1().CronJobs(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range cronJobs.Items {
+		refs = append(refs, podSpecReferences(ObjectRef{Kind: "CronJob", Namespace: w.Namespace, Name: w.Name}, w.Spec.JobTemplate.Spec.Template.Spec)...)
+	}
+
+	// ServiceAccounts
+	serviceAccounts, err := client.CoreV1().ServiceAccounts(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, sa := range serviceAccounts.Items {
+		from := ObjectRef{Kind: "ServiceAccount", Namespace: sa.Namespace, Name: sa.Name}
+		for _, secret := range sa.Secrets {
+			refs = append(refs, Reference{From: from, To: ObjectRef{Kind: "Secret", Namespace: sa.Namespace, Name: secret.Name}, Via: "secret"})
+		}
+		for _, pullSecret := range sa.ImagePullSecrets {
+			refs = append(refs, Reference{From: from, To: ObjectRef{Kind: "Secret", Namespace: sa.Namespace, Name: pullSecret.Name}, Via: "imagePullSecret"})
+		}
+	}
+
+	index := make(ReferenceIndex)
+	for _, ref := range refs {
+		index[ref.To] = append(index[ref.To], ref)
+	}
+	return index, nil
+}
+
+// referencePermissions returns the RBAC rules BuildReferenceIndex needs
+func referencePermissions() []rbacv1.PolicyRule {
+	return []rbacv1.PolicyRule{
+		{APIGroups: []string{""}, Resources: []string{"pods", "serviceaccounts"}, Verbs: []string{"list"}},
+		{APIGroups: []string{"apps"}, Resources: []string{"deployments", "statefulsets", "daemonsets"}, Verbs: []string{"list"}},
+		{APIGroups: []string{"batch"}, Resources: []string{"cronjobs"}, Verbs: []string{"list"}},
+	}
+}
+
+// podSpecReferences returns the ConfigMaps, Secrets, PVCs and the
+// ServiceAccount referenced by a pod spec
+func podSpecReferences(from ObjectRef, spec corev1.PodSpec) []Reference {
+	var refs []Reference
+	add := func(kind, name, via string, optional *bool) {
+		refs = append(refs, Reference{
+			From:     from,
+			To:       ObjectRef{Kind: kind, Namespace: from.Namespace, Name: name},
+			Via:      via,
+			Optional: optional != nil && *optional,
+		})
+	}
+
+	// Check volumes
+	for _, volume := range spec.Volumes {
+		switch {
+		case volume.ConfigMap != nil:
+			add("ConfigMap", volume.ConfigMap.Name, "volume", volume.ConfigMap.Optional)
+		case volume.Secret != nil:
+			add("Secret", volume.Secret.SecretName, "volume", volume.Secret.Optional)
+		case volume.PersistentVolumeClaim != nil:
+			add("PersistentVolumeClaim", volume.PersistentVolumeClaim.ClaimName, "volume", nil)
+		case volume.Projected != nil:
+			for _, source := range volume.Projected.Sources {
+				if source.ConfigMap != nil {
+					add("ConfigMap", source.ConfigMap.Name, "volume", source.ConfigMap.Optional)
+				}
+				if source.Secret != nil {
+					add("Secret", source.Secret.Name, "volume", source.Secret.Optional)
+				}
+			}
+		}
+	}
+
+	// Check environment variables of all containers
+	containers := append(append([]corev1.Container(nil), spec.InitContainers...), spec.Containers...)
+	for _, container := range containers {
+		for _, env := range container.Env {
+			if env.ValueFrom == nil {
+				continue
+			}
+			if ref := env.ValueFrom.ConfigMapKeyRef; ref != nil {
+				add("ConfigMap", ref.Name, "env", ref.Optional)
+			}
+			if ref := env.ValueFrom.SecretKeyRef; ref != nil {
+				add("Secret", ref.Name, "env", ref.Optional)
+			}
+		}
+
+		for _, envFrom := range container.EnvFrom {
+			if envFrom.ConfigMapRef != nil {
+				add("ConfigMap", envFrom.ConfigMapRef.Name, "envFrom", envFrom.ConfigMapRef.Optional)
+			}
+			if envFrom.SecretRef != nil {
+				add("Secret", envFrom.SecretRef.Name, "envFrom", envFrom.SecretRef.Optional)
+			}
+		}
+	}
+
+	// Check image pull secrets and the service account
+	for _, pullSecret := range spec.ImagePullSecrets {
+		add("Secret", pullSecret.Name, "imagePullSecret", nil)
+	}
+	if spec.ServiceAccountName != "" {
+		add("ServiceAccount", spec.ServiceAccountName, "serviceAccount", nil)
+	}
+
+	return refs
+}
@@ -0,0 +1,119 @@
+package resources
+
+import (
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+const (
+	// ReportAPIVersion identifies the version of the JSON and YAML output of scans
+	ReportAPIVersion = "k8s-pruner/v1"
+	// ReportKind identifies a PruneReport document
+	ReportKind = "PruneReport"
+)
+
+// PruneReport is the JSON and YAML form of the resources a scan found
+type PruneReport struct {
+	APIVersion string         `json:"apiVersion"`
+	Kind       string         `json:"kind"`
+	Resources  []ResourceList `json:"resources"`
+}
+
+// NewPruneReport wraps results in a versioned report
+func NewPruneReport(results []ResourceList) PruneReport {
+	if results == nil {
+		results = []ResourceList{}
+	}
+	return PruneReport{APIVersion: ReportAPIVersion, Kind: ReportKind, Resources: results}
+}
+
+// DeletionStatus is the outcome of deleting one item
+type DeletionStatus string
+
+const (
+	// StatusDeleted means the item was deleted
+	StatusDeleted DeletionStatus = "deleted"
+	// StatusNotFound means the item was already gone, which counts as success
+	StatusNotFound DeletionStatus = "notFound"
+	// StatusForbidden means RBAC denied the delete
+	StatusForbidden DeletionStatus = "forbidden"
+	// StatusConflict means the item changed since it was found
+	StatusConflict DeletionStatus = "conflict"
+	// StatusError is any other failure
+	StatusError DeletionStatus = "error"
+	// StatusSkipped means the item was deliberately not deleted; Reason says why
+	StatusSkipped DeletionStatus = "skipped"
+)
+
+// DeletionResult is the outcome of deleting one item
+type DeletionResult struct {
+	ResourceType string         `json:"resourceType"`
+	Name         string         `json:"name"`
+	Namespace    string         `json:"namespace"`
+	Status       DeletionStatus `json:"status"`
+	Reason       string         `json:"reason,omitempty"`
+	Error        string         `json:"error,omitempty"`
+}
+
+// Failed reports whether the item could not be removed
+func (r DeletionResult) Failed() bool {
+	return r.Status != StatusDeleted && r.Status != StatusNotFound && r.Status != StatusSkipped
+}
+
+// DeletionReport lists the outcome of every item passed to DeleteUnusedResources
+type DeletionReport struct {
+	// DryRun means nothing was deleted; Results say what would have happened
+	DryRun  bool             `json:"dryRun,omitempty"`
+	Results []DeletionResult `json:"results"`
+}
+
+// newDeletionResult records the outcome of deleting item
+func newDeletionResult(resourceType string, item ResourceItem, err error) DeletionResult {
+	result := DeletionResult{
+		ResourceType: resourceType,
+		Name:         item.Name,
+		Namespace:    item.Namespace,
+		Status:       statusFor(err),
+	}
+	if result.Failed() {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// Count returns the number of items with the given status
+func (r DeletionReport) Count(status DeletionStatus) int {
+	count := 0
+	for _, result := range r.Results {
+		if result.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// Failed returns the number of items that could not be removed
+func (r DeletionReport) Failed() int {
+	failed := 0
+	for _, result := range r.Results {
+		if result.Failed() {
+			failed++
+		}
+	}
+	return failed
+}
+
+// statusFor classifies a delete error
+func statusFor(err error) DeletionStatus {
+	switch {
+	case err == nil:
+		return StatusDeleted
+	case apierrors.IsNotFound(err):
+		return StatusNotFound
+	case apierrors.IsForbidden(err):
+		return StatusForbidden
+	case apierrors.IsConflict(err):
+		return StatusConflict
+	default:
+		return StatusError
+	}
+}
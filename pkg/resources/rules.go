@@ -0,0 +1,266 @@
+package resources
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Rule prunes finished custom resources, such as Argo Workflows or Tekton
+// PipelineRuns, through the dynamic client
+type Rule struct {
+	// RuleName is the resource type name used in --types
+	RuleName string
+	// ResourceType is the name shown in results and must be the one the
+	// rule is registered with; defaults to RuleName
+	ResourceType string
+	// Resource is the resource to prune
+	Resource schema.GroupVersionResource
+	// Match lists conditions that must all hold for an object to be pruned
+	Match []RuleMatch
+	// MinAge is how long an object must exist before it is pruned
+	MinAge time.Duration
+	// KeepLast keeps the newest N matching objects of each group
+	KeepLast int
+	// GroupBy is the label that groups objects for KeepLast; objects are
+	// always grouped by namespace as well
+	GroupBy string
+}
+
+// RuleMatch matches either a field value or a status condition
+type RuleMatch struct {
+	// Field is a dot-separated path such as "status.phase". With In, the
+	// value must be one of In; without, the field must be set.
+	Field string
+	In    []string
+
+	// Condition is a status.conditions type such as "Complete", which must
+	// have Status ("True" if empty)
+	Condition string
+	Status    string
+}
+
+// Name returns the resource type name
+func (r *Rule) Name() string {
+	return r.RuleName
+}
+
+// Find returns objects matching the rule, minus the newest KeepLast per group
+func (r *Rule) Find(ctx context.Context, clients Clients, opts FindOptions) (ResourceList, error) {
+	resourceType := r.ResourceType
+	if resourceType == "" {
+		resourceType = r.RuleName
+	}
+	result := ResourceList{
+		ResourceType: resourceType,
+		Items:        []ResourceItem{},
+	}
+
+	if clients.Dynamic == nil {
+		return result, fmt.Errorf("no dynamic client available")
+	}
+
+	// Get all objects
+	objects, err := clients.Dynamic.Resource(r.Resource).Namespace(opts.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: opts.LabelSelector,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	// Group the matching objects
+	groups := make(map[string][]unstructured.Unstructured)
+	for _, obj := range objects.Items {
+		if !r.matches(obj) {
+			continue
+		}
+		key := obj.GetNamespace() + "/" + obj.GetLabels()[r.GroupBy]
+		groups[key] = append(groups[key], obj)
+	}
+
+	minAgeCutoff := time.Now().Add(-r.MinAge)
+	for _, group := range groups {
+		// Newest first, so the first KeepLast objects are retained
+		sort.Slice(group, func(i, j int) bool {
+			return group[i].GetCreationTimestamp().Time.After(group[j].GetCreationTimestamp().Time)
+		})
+
+		for i, obj := range group {
+			if i < r.KeepLast {
+				continue
+			}
+
+			created := obj.GetCreationTimestamp().Time
+			if created.After(minAgeCutoff) || !opts.Window.Contains(created) {
+				continue
+			}
+
+			item := NewResourceItem(&obj)
+			item.Reason = fmt.Sprintf("matches rule %s", r.RuleName)
+			item.Reasons = []string{ReasonRuleMatched}
+			if r.KeepLast > 0 {
+				item.Reason += fmt.Sprintf(" and is not among the newest %d", r.KeepLast)
+				item.Reasons = append(item.Reasons, ReasonNotAmongNewest)
+			}
+			result.Items = append(result.Items, item)
+		}
+	}
+
+	// Map iteration is random, so sort for stable output
+	sort.Slice(result.Items, func(i, j int) bool {
+		a, b := result.Items[i], result.Items[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+
+	return result, nil
+}
+
+// Explain checks a single object the way Find does
+func (r *Rule) Explain(ctx context.Context, clients Clients, item ResourceItem) ([]Check, error) {
+	if clients.Dynamic == nil {
+		return nil, fmt.Errorf("no dynamic client available")
+	}
+	obj, err := clients.Dynamic.Resource(r.Resource).Namespace(item.Namespace).Get(ctx, item.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	// Each condition of the rule
+	var checks []Check
+	for _, m := range r.Match {
+		if m.Field != "" {
+			value, found, _ := unstructured.NestedFieldNoCopy(obj.Object, strings.Split(m.Field, ".")...)
+			actual := "not set"
+			if found && value != nil {
+				actual = fmt.Sprintf("is %v", value)
+			}
+			detail := fmt.Sprintf("%s %s, must be set", m.Field, actual)
+			if len(m.In) > 0 {
+				detail = fmt.Sprintf("%s %s, must be one of %s", m.Field, actual, strings.Join(m.In, ", "))
+			}
+			checks = append(checks, Check{Name: "match", Passed: matchField(*obj, m), Detail: detail})
+		}
+		if m.Condition != "" {
+			expected := m.Status
+			if expected == "" {
+				expected = "True"
+			}
+			checks = append(checks, Check{
+				Name:   "match",
+				Passed: matchCondition(*obj, m),
+				Detail: fmt.Sprintf("condition %s must be %s", m.Condition, expected),
+			})
+		}
+	}
+
+	minAge := Check{Name: "min age", Passed: true, Detail: "the rule has no minimum age"}
+	if r.MinAge > 0 {
+		minAge.Passed = !obj.GetCreationTimestamp().Time.After(time.Now().Add(-r.MinAge))
+		minAge.Detail = fmt.Sprintf("must be older than %s", r.MinAge)
+	}
+	checks = append(checks, minAge)
+
+	// Its position among the matching objects of its group
+	if r.KeepLast > 0 {
+		objects, err := clients.Dynamic.Resource(r.Resource).Namespace(item.Namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		newer := 0
+		for _, other := range objects.Items {
+			if other.GetLabels()[r.GroupBy] == obj.GetLabels()[r.GroupBy] && r.matches(other) &&
+				other.GetCreationTimestamp().Time.After(obj.GetCreationTimestamp().Time) {
+				newer++
+			}
+		}
+		checks = append(checks, Check{
+			Name:   "keep last",
+			Passed: newer >= r.KeepLast,
+			Detail: fmt.Sprintf("%d newer matching objects in its group; the newest %d are kept", newer, r.KeepLast),
+		})
+	}
+
+	return checks, nil
+}
+
+// matches reports whether obj satisfies every RuleMatch
+func (r *Rule) matches(obj unstructured.Unstructured) bool {
+	for _, m := range r.Match {
+		if m.Field != "" && !matchField(obj, m) {
+			return false
+		}
+		if m.Condition != "" && !matchCondition(obj, m) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchField checks a field value against RuleMatch.In
+func matchField(obj unstructured.Unstructured, m RuleMatch) bool {
+	value, found, err := unstructured.NestedFieldNoCopy(obj.Object, strings.Split(m.Field, ".")...)
+	if err != nil || !found || value == nil {
+		return false
+	}
+	if len(m.In) == 0 {
+		return true
+	}
+
+	actual := fmt.Sprint(value)
+	for _, expected := range m.In {
+		if actual == expected {
+			return true
+		}
+	}
+	return false
+}
+
+// matchCondition checks for a status condition with the expected status
+func matchCondition(obj unstructured.Unstructured, m RuleMatch) bool {
+	expected := m.Status
+	if expected == "" {
+		expected = "True"
+	}
+
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if condition["type"] == m.Condition {
+			return condition["status"] == expected
+		}
+	}
+	return false
+}
+
+// Delete deletes a single object through the dynamic client
+func (r *Rule) Delete(ctx context.Context, clients Clients, item ResourceItem, opts metav1.DeleteOptions) error {
+	return DeleteDynamic(ctx, clients, r.Resource, item, opts)
+}
+
+// RequiredPermissions returns the RBAC rules Find and Delete need
+func (r *Rule) RequiredPermissions() []rbacv1.PolicyRule {
+	return []rbacv1.PolicyRule{
+		{APIGroups: []string{r.Resource.Group}, Resources: []string{r.Resource.Resource}, Verbs: []string{"list", "delete"}},
+	}
+}
+
+// DeleteDynamic deletes item, a resource of type gvr, through the dynamic client
+func DeleteDynamic(ctx context.Context, clients Clients, gvr schema.GroupVersionResource, item ResourceItem, opts metav1.DeleteOptions) error {
+	if clients.Dynamic == nil {
+		return fmt.Errorf("no dynamic client available")
+	}
+	return clients.Dynamic.Resource(gvr).Namespace(item.Namespace).Delete(ctx, item.Name, opts)
+}
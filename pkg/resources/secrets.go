@@ -2,116 +2,121 @@ package resources
 
 import (
 	"context"
-	"time"
+	"fmt"
 
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-// FindUnusedSecrets finds Secrets that are not mounted in any Pod
-func (d *ResourceDetector) FindUnusedSecrets(namespace string, olderThan *time.Time, labelSelector string) (ResourceList, error) {
-	ctx := context.Background()
+// secretDetector finds Secrets that no Pod, workload or ServiceAccount references
+type secretDetector struct{}
+
+// Name returns the canonical type name
+func (secretDetector) Name() string {
+	return "secrets"
+}
+
+// Find returns Secrets that no Pod, workload or ServiceAccount references
+func (secretDetector) Find(ctx context.Context, clients Clients, opts FindOptions) (ResourceList, error) {
 	result := ResourceList{
 		ResourceType: "Secrets",
 		Items:        []ResourceItem{},
 	}
 
 	// Get all Secrets
-	secrets, err := d.client.CoreV1().Secrets(namespace).List(ctx, metav1.ListOptions{
-		LabelSelector: labelSelector,
+	secrets, err := clients.Kube.CoreV1().Secrets(opts.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: opts.LabelSelector,
 	})
 	if err != nil {
 		return result, err
 	}
 
-	// Get all Pods
-	pods, err := d.client.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	// Track which Secrets Pods, workload templates and ServiceAccounts reference
+	references, err := BuildReferenceIndex(ctx, clients.Kube, opts.Namespace)
 	if err != nil {
 		return result, err
 	}
+	usedSecrets := references.referenced("Secret")
 
-	// Track which Secrets are in use
-	usedSecrets := make(map[string]bool)
+	// Remember what is in use for future --unused-for checks
+	opts.RecordUsage("Secrets", usedSecrets)
 
-	// Check Secret usage in Pods
-	for _, pod := range pods.Items {
-		// Check volume mounts
-		for _, volume := range pod.Spec.Volumes {
-			if volume.Secret != nil {
-				key := pod.Namespace + "/" + volume.Secret.SecretName
-				usedSecrets[key] = true
-			}
+	// Add unused Secrets to result
+	for _, secret := range secrets.Items {
+		key := secret.Namespace + "/" + secret.Name
+
+		// Skip service account tokens, TLS secrets, and system secrets
+		if isProtectedSecret(secret) {
+			continue
 		}
 
-		// Check environment variables
-		for _, container := range pod.Spec.Containers {
-			for _, env := range container.Env {
-				if env.ValueFrom != nil && env.ValueFrom.SecretKeyRef != nil {
-					key := pod.Namespace + "/" + env.ValueFrom.SecretKeyRef.Name
-					usedSecrets[key] = true
-				}
+		// Check if Secret is unused
+		if !usedSecrets[key] {
+			// Check the creation time window
+			if !opts.Window.Contains(secret.CreationTimestamp.Time) {
+				continue
 			}
 
-			// Check envFrom
-			for _, envFrom := range container.EnvFrom {
-				if envFrom.SecretRef != nil {
-					key := pod.Namespace + "/" + envFrom.SecretRef.Name
-					usedSecrets[key] = true
-				}
+			// Check that it also stayed unused for the --unused-for window
+			if !opts.UnusedLongEnough("Secrets", key) {
+				continue
 			}
-		}
 
-		// Check image pull secrets
-		for _, pullSecret := range pod.Spec.ImagePullSecrets {
-			key := pod.Namespace + "/" + pullSecret.Name
-			usedSecrets[key] = true
+			item := NewResourceItem(&secret)
+			item.Reason = "not referenced by any pod, workload or service account"
+			item.Reasons = []string{ReasonUnreferenced}
+			result.Items = append(result.Items, item)
 		}
 	}
 
-	// Get all ServiceAccounts to check for token secrets
-	serviceAccounts, err := d.client.CoreV1().ServiceAccounts(namespace).List(ctx, metav1.ListOptions{})
+	return result, nil
+}
+
+// Explain checks a single Secret the way Find does
+func (d secretDetector) Explain(ctx context.Context, clients Clients, item ResourceItem) ([]Check, error) {
+	references, err := BuildReferenceIndex(ctx, clients.Kube, item.Namespace)
 	if err != nil {
-		return result, err
+		return nil, err
 	}
+	return d.explainReferences(ctx, clients, item, references)
+}
 
-	// Check Secret usage in ServiceAccounts
-	for _, sa := range serviceAccounts.Items {
-		for _, secret := range sa.Secrets {
-			key := sa.Namespace + "/" + secret.Name
-			usedSecrets[key] = true
-		}
-		if sa.ImagePullSecrets != nil {
-			for _, pullSecret := range sa.ImagePullSecrets {
-				key := sa.Namespace + "/" + pullSecret.Name
-				usedSecrets[key] = true
-			}
-		}
+// explainReferences checks a single Secret against references
+func (secretDetector) explainReferences(ctx context.Context, clients Clients, item ResourceItem, references ReferenceIndex) ([]Check, error) {
+	secret, err := clients.Kube.CoreV1().Secrets(item.Namespace).Get(ctx, item.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
 	}
 
-	// Add unused Secrets to result
-	for _, secret := range secrets.Items {
-		key := secret.Namespace + "/" + secret.Name
+	checks := []Check{{
+		Name:   "system",
+		Passed: !isProtectedSecret(*secret),
+		Detail: fmt.Sprintf("type %s; service account tokens, TLS Secrets and Secrets in kube-system are never pruned", secret.Type),
+	}}
 
-		// Skip service account tokens, TLS secrets, and system secrets
-		if secret.Type == "kubernetes.io/service-account-token" ||
-			secret.Type == "kubernetes.io/tls" ||
-			secret.Namespace == "kube-system" {
-			continue
-		}
+	// Find the Pods, workloads and ServiceAccounts using it
+	users := references.ReferencedBy(ObjectRef{Kind: "Secret", Namespace: secret.Namespace, Name: secret.Name})
 
-		// Check if Secret is unused
-		if !usedSecrets[key] {
-			// Check age if filter is provided
-			if olderThan != nil && secret.CreationTimestamp.Time.After(*olderThan) {
-				continue
-			}
+	return append(checks, usageCheck("not referenced by any pod, workload or service account", users)), nil
+}
 
-			result.Items = append(result.Items, ResourceItem{
-				Name:      secret.Name,
-				Namespace: secret.Namespace,
-				Age:       secret.CreationTimestamp.Time,
-			})
-		}
-	}
+// Delete deletes a single Secret
+func (secretDetector) Delete(ctx context.Context, clients Clients, item ResourceItem, opts metav1.DeleteOptions) error {
+	return clients.Kube.CoreV1().Secrets(item.Namespace).Delete(ctx, item.Name, opts)
+}
 
-	return result, nil
+// RequiredPermissions returns the RBAC rules Find and Delete need
+func (secretDetector) RequiredPermissions() []rbacv1.PolicyRule {
+	return append([]rbacv1.PolicyRule{
+		{APIGroups: []string{""}, Resources: []string{"secrets"}, Verbs: []string{"list", "delete"}},
+	}, referencePermissions()...)
+}
+
+// isProtectedSecret returns true for Secrets that are never pruned: service
+// account tokens, TLS secrets, and system secrets
+func isProtectedSecret(secret corev1.Secret) bool {
+	return secret.Type == corev1.SecretTypeServiceAccountToken ||
+		secret.Type == corev1.SecretTypeTLS ||
+		secret.Namespace == "kube-system"
 }
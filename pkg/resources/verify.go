@@ -0,0 +1,95 @@
+package resources
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// VerifyStillUnused re-runs detection for the types in results right before
+// deleting, with the same filters as the original scan. It returns the items
+// that are still candidates, and a skipped result for each item that isn't,
+// e.g. a ConfigMap a pod started mounting while the prompt was open.
+func (d *ResourceDetector) VerifyStillUnused(results []ResourceList, namespace string, windows TimeWindows, labelSelector string) ([]ResourceList, []DeletionResult, error) {
+	var types []string
+	kindsByType := make(map[string]Kind)
+	for _, resourceList := range results {
+		kind, ok := KindForResourceType(resourceList.ResourceType)
+		if !ok {
+			return nil, nil, fmt.Errorf("unknown resource type %q", resourceList.ResourceType)
+		}
+		types = append(types, kind.Name)
+		kindsByType[resourceList.ResourceType] = kind
+	}
+	if len(types) == 0 {
+		return results, nil, nil
+	}
+
+	fresh, err := d.FindAllUnusedResources(namespace, windows, types, labelSelector)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Index the fresh candidates by type and namespace/name
+	current := make(map[string]ResourceItem)
+	for _, resourceList := range fresh {
+		for _, item := range resourceList.Items {
+			current[resourceList.ResourceType+"/"+item.Namespace+"/"+item.Name] = item
+		}
+	}
+
+	var verified []ResourceList
+	var skipped []DeletionResult
+	for _, resourceList := range results {
+		kept := ResourceList{ResourceType: resourceList.ResourceType, Items: []ResourceItem{}}
+
+		for _, item := range resourceList.Items {
+			latest, ok := current[resourceList.ResourceType+"/"+item.Namespace+"/"+item.Name]
+
+			if ok && (item.UID == "" || latest.UID == item.UID) {
+				kept.Items = append(kept.Items, item)
+				continue
+			}
+
+			// A recreated object with the same name is a different object
+			reason := "recreated (UID changed)"
+			if !ok {
+				reason = d.dropReason(kindsByType[resourceList.ResourceType], item)
+			}
+
+			skipped = append(skipped, DeletionResult{
+				ResourceType: resourceList.ResourceType,
+				Name:         item.Name,
+				Namespace:    item.Namespace,
+				Status:       StatusSkipped,
+				Reason:       reason,
+			})
+		}
+
+		if len(kept.Items) > 0 {
+			verified = append(verified, kept)
+		}
+	}
+
+	return verified, skipped, nil
+}
+
+// dropReason says why item, of the given kind, is no longer a candidate. It
+// looks the object up to tell a deleted or recreated object from one that is
+// now in use, and assumes the latter if the kind's resource isn't known.
+func (d *ResourceDetector) dropReason(kind Kind, item ResourceItem) string {
+	if kind.Resource.Resource == "" || d.clients.Dynamic == nil {
+		return "now in use"
+	}
+
+	obj, err := d.clients.Dynamic.Resource(kind.Resource).Namespace(item.Namespace).Get(context.Background(), item.Name, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		return "no longer exists"
+	case err == nil && item.UID != "" && obj.GetUID() != item.UID:
+		return "recreated (UID changed)"
+	}
+	return "now in use"
+}
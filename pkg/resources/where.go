@@ -0,0 +1,125 @@
+package resources
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/manthan-parmar-1998/k8s-pruner/pkg/policy"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// UseFilters makes FindAllUnusedResources keep only the candidates that
+// match every --where expression of their type
+func (d *ResourceDetector) UseFilters(filters policy.Filters) {
+	d.filters = filters
+}
+
+// filterScope caches what expressions need across types during one scan
+type filterScope struct {
+	namespace       string
+	references      ReferenceIndex
+	namespaceLabels map[string]map[string]string
+}
+
+// filterCandidates drops the items of list that don't match expressions
+func (d *ResourceDetector) filterCandidates(ctx context.Context, scope *filterScope, kind Kind, expressions []*policy.Expression, labelSelector string, list ResourceList) (ResourceList, error) {
+	if kind.Resource.Resource == "" {
+		return list, fmt.Errorf("--where %s=... needs the API resource of %s, which its detector doesn't declare", kind.Name, kind.Name)
+	}
+	if d.clients.Dynamic == nil {
+		return list, fmt.Errorf("no dynamic client available")
+	}
+
+	// Get the full objects the expressions are evaluated against
+	objects, err := d.clients.Dynamic.Resource(kind.Resource).Namespace(scope.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: labelSelector,
+	})
+	if err != nil {
+		return list, err
+	}
+	byKey := make(map[string]unstructured.Unstructured)
+	for _, obj := range objects.Items {
+		byKey[obj.GetNamespace()+"/"+obj.GetName()] = obj
+	}
+
+	if err := d.loadFilterScope(ctx, scope); err != nil {
+		return list, err
+	}
+
+	filtered := list
+	filtered.Items = []ResourceItem{}
+	for _, item := range list.Items {
+		obj, ok := byKey[item.Namespace+"/"+item.Name]
+		if !ok {
+			// Deleted since it was found
+			continue
+		}
+
+		vars, err := scope.variables(obj)
+		if err != nil {
+			return list, err
+		}
+
+		matched := true
+		for _, expression := range expressions {
+			ok, err := expression.Matches(vars)
+			if err != nil {
+				return list, fmt.Errorf("%s %s/%s: %v", kind.ResourceType, item.Namespace, item.Name, err)
+			}
+			if !ok {
+				matched = false
+				break
+			}
+		}
+
+		if matched {
+			filtered.Items = append(filtered.Items, item)
+		}
+	}
+
+	return filtered, nil
+}
+
+// variables returns the inputs of expressions evaluated against obj
+func (scope *filterScope) variables(obj unstructured.Unstructured) (policy.Variables, error) {
+	data, err := json.Marshal(obj.Object)
+	if err != nil {
+		return policy.Variables{}, err
+	}
+
+	return policy.Variables{
+		Object:          obj.Object,
+		Age:             time.Since(obj.GetCreationTimestamp().Time),
+		ReferencedBy:    scope.references.ReferencedBy(ObjectRef{Kind: obj.GetKind(), Namespace: obj.GetNamespace(), Name: obj.GetName()}),
+		NamespaceLabels: scope.namespaceLabels[obj.GetNamespace()],
+		Size:            len(data),
+	}, nil
+}
+
+// loadFilterScope fetches references and namespace labels the first time
+// they are needed in a scan
+func (d *ResourceDetector) loadFilterScope(ctx context.Context, scope *filterScope) error {
+	if scope.references != nil {
+		return nil
+	}
+
+	references, err := BuildReferenceIndex(ctx, d.clients.Kube, scope.namespace)
+	if err != nil {
+		return err
+	}
+
+	namespaces, err := d.clients.Kube.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	scope.namespaceLabels = make(map[string]map[string]string)
+	for _, ns := range namespaces.Items {
+		scope.namespaceLabels[ns.Name] = ns.Labels
+	}
+
+	scope.references = references
+	return nil
+}
@@ -0,0 +1,37 @@
+package resources
+
+import "time"
+
+// TimeWindow restricts resources by creation time. A nil bound is open.
+type TimeWindow struct {
+	// After only admits resources created after this time (--newer-than)
+	After *time.Time
+	// Before only admits resources created before this time (--age, --older-than, --before)
+	Before *time.Time
+}
+
+// Contains reports whether a resource created at t falls inside the window
+func (w TimeWindow) Contains(t time.Time) bool {
+	if w.Before != nil && t.After(*w.Before) {
+		return false
+	}
+	if w.After != nil && t.Before(*w.After) {
+		return false
+	}
+	return true
+}
+
+// TimeWindows holds a creation time window per resource type (as named in
+// --types). Types without their own entry use Default.
+type TimeWindows struct {
+	Default TimeWindow
+	PerType map[string]TimeWindow
+}
+
+// For returns the time window that applies to resourceType
+func (w TimeWindows) For(resourceType string) TimeWindow {
+	if window, ok := w.PerType[resourceType]; ok {
+		return window
+	}
+	return w.Default
+}
@@ -0,0 +1,162 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"k8s.io/client-go/util/homedir"
+)
+
+// DefaultMaxScanGap is the longest time between two scans that still counts
+// as continuous history, enough for a daily CronJob that starts a bit late
+const DefaultMaxScanGap = 25 * time.Hour
+
+// Store records when objects were last seen referenced across scans, so that
+// objects used only now and then (e.g. by a weekly batch pod) are not
+// mistaken for unused ones
+type Store struct {
+	path string
+
+	// MaxScanGap is the longest time between two scans that still counts as
+	// continuous history. An object used only during a longer gap could have
+	// been missed, so a gap restarts the history.
+	MaxScanGap time.Duration `json:"-"`
+
+	// Scans holds the periods of continuous scanning per resource type and
+	// namespace scope ("ConfigMaps/team-a", or "ConfigMaps/" for all namespaces)
+	Scans map[string]ScanPeriod `json:"scans"`
+
+	// LastUsed holds the last time each object was seen referenced, keyed by
+	// resource type and "namespace/name" ("ConfigMaps/team-a/app-config")
+	LastUsed map[string]time.Time `json:"lastUsed"`
+}
+
+// DefaultPath returns the default location of the state file
+func DefaultPath() string {
+	return filepath.Join(homedir.HomeDir(), ".k8s-pruner", "state.json")
+}
+
+// Load reads the state file at path. A missing file yields an empty store.
+func Load(path string) (*Store, error) {
+	store := &Store{
+		path:       path,
+		MaxScanGap: DefaultMaxScanGap,
+		Scans:      map[string]ScanPeriod{},
+		LastUsed:   map[string]time.Time{},
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading state file: %v", err)
+	}
+
+	if err := json.Unmarshal(data, store); err != nil {
+		return nil, fmt.Errorf("error parsing state file %s: %v", path, err)
+	}
+	if store.Scans == nil {
+		store.Scans = map[string]ScanPeriod{}
+	}
+	if store.LastUsed == nil {
+		store.LastUsed = map[string]time.Time{}
+	}
+
+	return store, nil
+}
+
+// Save writes the store back to the file it was loaded from
+func (s *Store) Save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return fmt.Errorf("error creating state directory: %v", err)
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling state: %v", err)
+	}
+
+	// Write to a temporary file first so an interrupted run can't corrupt the history
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("error writing state file: %v", err)
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// RecordScan notes that resourceType was scanned in namespace ("" for all
+// namespaces) at the given time. A scan within MaxScanGap of the previous one
+// extends the current period; after a longer gap a new period starts.
+func (s *Store) RecordScan(resourceType, namespace string, at time.Time) {
+	key := resourceType + "/" + namespace
+	period, ok := s.Scans[key]
+	if !ok || at.After(period.Last.Add(s.MaxScanGap)) {
+		s.Scans[key] = ScanPeriod{First: at, Last: at}
+		return
+	}
+	if at.After(period.Last) {
+		period.Last = at
+		s.Scans[key] = period
+	}
+}
+
+// MarkUsed records that the object identified by key ("namespace/name") was
+// seen referenced at the given time
+func (s *Store) MarkUsed(resourceType, key string, at time.Time) {
+	s.LastUsed[resourceType+"/"+key] = at
+}
+
+// UnusedSince reports whether the object identified by key ("namespace/name")
+// has been unreferenced in every scan since the given time. It returns false
+// when the recorded history doesn't reach back that far without a gap.
+func (s *Store) UnusedSince(resourceType, key string, since time.Time) bool {
+	namespace, _, _ := strings.Cut(key, "/")
+	if !s.coversSince(resourceType, namespace, since) {
+		return false
+	}
+
+	lastUsed, ok := s.LastUsed[resourceType+"/"+key]
+	return !ok || lastUsed.Before(since)
+}
+
+// coversSince reports whether the current period of scans of resourceType in
+// namespace started at or before the given time
+func (s *Store) coversSince(resourceType, namespace string, since time.Time) bool {
+	for _, key := range []string{resourceType + "/" + namespace, resourceType + "/"} {
+		if period, ok := s.Scans[key]; ok && !period.First.After(since) {
+			return true
+		}
+	}
+	return false
+}
+
+// ScanPeriod is a stretch of scans with no gap longer than MaxScanGap
+type ScanPeriod struct {
+	First time.Time `json:"first"`
+	Last  time.Time `json:"last"`
+}
+
+// UnmarshalJSON also reads the single first scan time older state files
+// recorded. Whether scans continued after it is unknown, so the history
+// restarts at the next scan unless that falls within MaxScanGap of it.
+func (p *ScanPeriod) UnmarshalJSON(data []byte) error {
+	var first time.Time
+	if err := json.Unmarshal(data, &first); err == nil {
+		*p = ScanPeriod{First: first, Last: first}
+		return nil
+	}
+
+	// A plain struct type, so decoding doesn't recurse into this method
+	type scanPeriod ScanPeriod
+	var period scanPeriod
+	if err := json.Unmarshal(data, &period); err != nil {
+		return err
+	}
+	*p = ScanPeriod(period)
+	return nil
+}
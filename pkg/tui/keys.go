@@ -0,0 +1,118 @@
+package tui
+
+import (
+	"bufio"
+	"unicode/utf8"
+)
+
+// Key is a decoded key press: one of the named keys below, or the typed
+// character itself
+type Key string
+
+// Named keys
+const (
+	KeyUp        Key = "up"
+	KeyDown      Key = "down"
+	KeyLeft      Key = "left"
+	KeyRight     Key = "right"
+	KeyHome      Key = "home"
+	KeyEnd       Key = "end"
+	KeyPageUp    Key = "pgup"
+	KeyPageDown  Key = "pgdown"
+	KeyEnter     Key = "enter"
+	KeyEsc       Key = "esc"
+	KeyBackspace Key = "backspace"
+	KeyTab       Key = "tab"
+	KeyCtrlC     Key = "ctrl+c"
+	KeyUnknown   Key = "unknown"
+)
+
+// ReadKey reads one key press from a terminal in raw mode, decoding the
+// escape sequences of arrow and navigation keys
+func ReadKey(r *bufio.Reader) (Key, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return "", err
+	}
+
+	switch b {
+	case 3:
+		return KeyCtrlC, nil
+	case '\r', '\n':
+		return KeyEnter, nil
+	case 127, 8:
+		return KeyBackspace, nil
+	case '\t':
+		return KeyTab, nil
+	case 27:
+		// A lone ESC is the Escape key; anything already buffered after it
+		// is an escape sequence
+		if r.Buffered() == 0 {
+			return KeyEsc, nil
+		}
+		return readEscapeSequence(r)
+	}
+
+	if b < utf8.RuneSelf {
+		return Key(string(rune(b))), nil
+	}
+	if err := r.UnreadByte(); err != nil {
+		return "", err
+	}
+	ch, _, err := r.ReadRune()
+	if err != nil {
+		return "", err
+	}
+	return Key(string(ch)), nil
+}
+
+// readEscapeSequence decodes the rest of a CSI or SS3 sequence after ESC
+func readEscapeSequence(r *bufio.Reader) (Key, error) {
+	introducer, err := r.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	if introducer != '[' && introducer != 'O' {
+		return KeyUnknown, nil
+	}
+
+	// Parameters, then a final byte
+	var params []byte
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		if b >= '0' && b <= '9' || b == ';' {
+			params = append(params, b)
+			continue
+		}
+
+		switch b {
+		case 'A':
+			return KeyUp, nil
+		case 'B':
+			return KeyDown, nil
+		case 'C':
+			return KeyRight, nil
+		case 'D':
+			return KeyLeft, nil
+		case 'H':
+			return KeyHome, nil
+		case 'F':
+			return KeyEnd, nil
+		case '~':
+			switch string(params) {
+			case "1", "7":
+				return KeyHome, nil
+			case "4", "8":
+				return KeyEnd, nil
+			case "5":
+				return KeyPageUp, nil
+			case "6":
+				return KeyPageDown, nil
+			}
+		}
+		return KeyUnknown, nil
+	}
+}
@@ -0,0 +1,648 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/manthan-parmar-1998/k8s-pruner/pkg/resources"
+	"github.com/manthan-parmar-1998/k8s-pruner/pkg/utils"
+)
+
+// Backend is what the TUI needs from the cluster. The prune command's
+// deletion path is plugged in here, so the TUI deletes exactly like prune.
+type Backend interface {
+	// Manifest returns the YAML of an item
+	Manifest(resourceType string, item resources.ResourceItem) (string, error)
+	// Delete deletes the selected items
+	Delete(selection []resources.ResourceList) (resources.DeletionReport, error)
+}
+
+// ANSI sequences used by View
+const (
+	inverse = "\x1b[7m"
+	bold    = "\x1b[1m"
+	reset   = "\x1b[0m"
+)
+
+// rowKind is the level of a row in the tree
+type rowKind int
+
+const (
+	typeRow rowKind = iota
+	namespaceRow
+	itemRow
+)
+
+// row is one visible line of the tree
+type row struct {
+	kind         rowKind
+	resourceType string
+	namespace    string
+	item         resources.ResourceItem
+	// items are the visible items under the row, or the row's own item
+	items []string
+}
+
+// Model is the state of the TUI. It only depends on the keys it is given
+// and the Backend, so it can be driven without a real terminal.
+type Model struct {
+	results []resources.ResourceList
+	refs    resources.ReferenceIndex
+	backend Backend
+
+	width, height int
+	cursor        int
+	offset        int
+	// detailsOffset scrolls the details pane; it resets when the cursor moves
+	detailsOffset int
+
+	// expanded holds the keys of open type and namespace rows
+	expanded map[string]bool
+	// selected holds the keys of selected items
+	selected map[string]bool
+	// referenced holds the keys of items something still references. They
+	// are only selected one at a time, never by a or a group.
+	referenced map[string]bool
+
+	filter       string
+	filtering    bool
+	confirming   bool
+	showManifest bool
+	manifests    map[string]string
+	status       string
+	done         bool
+}
+
+// New creates a model browsing results. Types start expanded.
+func New(results []resources.ResourceList, refs resources.ReferenceIndex, backend Backend) *Model {
+	m := &Model{
+		results:    results,
+		refs:       refs,
+		backend:    backend,
+		width:      80,
+		height:     24,
+		expanded:   make(map[string]bool),
+		selected:   make(map[string]bool),
+		referenced: make(map[string]bool),
+		manifests:  make(map[string]string),
+	}
+	for _, resourceList := range results {
+		m.expanded[resourceList.ResourceType] = true
+		for _, item := range resourceList.Items {
+			if references, _ := refs.ReferencesTo(resourceList.ResourceType, item); len(references) > 0 {
+				m.referenced[itemKey(resourceList.ResourceType, item)] = true
+			}
+		}
+	}
+	m.status = "space select · enter expand · / filter · m manifest · [ ] scroll · d delete · q quit"
+	return m
+}
+
+// Done reports whether the user quit
+func (m *Model) Done() bool {
+	return m.done
+}
+
+// SetSize tells the model the terminal size
+func (m *Model) SetSize(width, height int) {
+	if width > 0 && height > 0 {
+		m.width, m.height = width, height
+	}
+	m.scroll()
+}
+
+// Selected returns the selected items, grouped by type
+func (m *Model) Selected() []resources.ResourceList {
+	var selection []resources.ResourceList
+	for _, resourceList := range m.results {
+		picked := resources.ResourceList{ResourceType: resourceList.ResourceType, Items: []resources.ResourceItem{}}
+		for _, item := range resourceList.Items {
+			if m.selected[itemKey(resourceList.ResourceType, item)] {
+				picked.Items = append(picked.Items, item)
+			}
+		}
+		if len(picked.Items) > 0 {
+			selection = append(selection, picked)
+		}
+	}
+	return selection
+}
+
+// Update applies one key press
+func (m *Model) Update(key Key) {
+	previous := m.cursor
+	switch {
+	case key == KeyCtrlC:
+		m.done = true
+	case m.confirming:
+		m.confirming = false
+		if key == "y" || key == "Y" {
+			m.deleteSelected()
+		} else {
+			m.status = "Delete cancelled."
+		}
+	case m.filtering:
+		m.updateFilter(key)
+	default:
+		m.updateBrowse(key)
+	}
+
+	m.clampCursor()
+	m.scroll()
+	if m.cursor != previous {
+		m.detailsOffset = 0
+	}
+	if m.showManifest {
+		m.loadManifest()
+	}
+}
+
+// updateFilter handles keys while the filter is being typed
+func (m *Model) updateFilter(key Key) {
+	switch key {
+	case KeyEnter:
+		m.filtering = false
+	case KeyEsc:
+		m.filtering = false
+		m.filter = ""
+	case KeyBackspace:
+		if runes := []rune(m.filter); len(runes) > 0 {
+			m.filter = string(runes[:len(runes)-1])
+		}
+	default:
+		if len([]rune(string(key))) == 1 {
+			m.filter += string(key)
+		}
+	}
+	m.cursor = 0
+}
+
+// updateBrowse handles keys while moving through the tree
+func (m *Model) updateBrowse(key Key) {
+	rows := m.rows()
+	pageSize := m.bodyHeight()
+
+	switch key {
+	case "q", KeyEsc:
+		m.done = true
+	case KeyUp, "k":
+		m.cursor--
+	case KeyDown, "j":
+		m.cursor++
+	case KeyPageUp:
+		m.cursor -= pageSize
+	case KeyPageDown:
+		m.cursor += pageSize
+	case KeyHome, "g":
+		m.cursor = 0
+	case KeyEnd, "G":
+		m.cursor = len(rows) - 1
+	case KeyRight, "l", KeyEnter:
+		if m.cursor < len(rows) && rows[m.cursor].kind != itemRow {
+			m.expanded[rowKey(rows[m.cursor])] = true
+		}
+	case KeyLeft, "h":
+		m.collapse(rows)
+	case " ":
+		m.toggle(rows)
+	case "a":
+		// Select everything visible that nothing references
+		for _, r := range rows {
+			for _, key := range r.items {
+				if !m.referenced[key] {
+					m.selected[key] = true
+				}
+			}
+		}
+	case "n":
+		m.selected = make(map[string]bool)
+	case "/":
+		m.filtering = true
+	case "m":
+		m.showManifest = !m.showManifest
+	case "]":
+		m.detailsOffset += pageSize / 2
+	case "[":
+		m.detailsOffset -= pageSize / 2
+		if m.detailsOffset < 0 {
+			m.detailsOffset = 0
+		}
+	case "d":
+		count := len(m.selected)
+		if count == 0 {
+			m.status = "Nothing selected; press space to select resources."
+			return
+		}
+		m.confirming = true
+		m.status = fmt.Sprintf("Delete %d resources? (y/N)", count)
+		if referenced := m.countReferenced(); referenced > 0 {
+			m.status = fmt.Sprintf("Delete %d resources, %d still referenced? (y/N)", count, referenced)
+		}
+	}
+}
+
+// collapse closes the row under the cursor, or moves to its parent
+func (m *Model) collapse(rows []row) {
+	if m.cursor >= len(rows) {
+		return
+	}
+	current := rows[m.cursor]
+	if current.kind != itemRow && m.expanded[rowKey(current)] {
+		m.expanded[rowKey(current)] = false
+		return
+	}
+
+	// Move to the parent row
+	for i := m.cursor - 1; i >= 0; i-- {
+		if rows[i].kind < current.kind {
+			m.cursor = i
+			return
+		}
+	}
+}
+
+// toggle selects or deselects every item under the cursor. A type or
+// namespace row leaves out the items something references.
+func (m *Model) toggle(rows []row) {
+	if m.cursor >= len(rows) {
+		return
+	}
+	current := rows[m.cursor]
+	var keys []string
+	for _, key := range current.items {
+		if current.kind == itemRow || !m.referenced[key] {
+			keys = append(keys, key)
+		}
+	}
+	all := m.countSelected(keys) == len(keys)
+	for _, key := range keys {
+		if all {
+			delete(m.selected, key)
+		} else {
+			m.selected[key] = true
+		}
+	}
+}
+
+// deleteSelected deletes the selection through the backend and drops the
+// items that are gone from the tree
+func (m *Model) deleteSelected() {
+	report, err := m.backend.Delete(m.Selected())
+	if err != nil {
+		m.status = "Error: " + err.Error()
+		return
+	}
+
+	gone := make(map[string]bool)
+	for _, result := range report.Results {
+		if !result.Failed() && result.Status != resources.StatusSkipped {
+			gone[result.ResourceType+"/"+result.Namespace+"/"+result.Name] = true
+		}
+	}
+
+	failed := report.Failed()
+	skipped := report.Count(resources.StatusSkipped)
+	if report.DryRun {
+		m.status = fmt.Sprintf("DRY RUN: %d resources would be deleted, %d would fail, %d skipped.", len(gone), failed, skipped)
+		return
+	}
+	m.status = fmt.Sprintf("Deleted %d resources, %d failed, %d skipped.", len(gone), failed, skipped)
+	for _, result := range report.Results {
+		if result.Failed() {
+			m.status += fmt.Sprintf(" %s/%s: %s", result.Namespace, result.Name, result.Error)
+			break
+		}
+	}
+
+	// Remove what was deleted, keeping failures selected for another try
+	for i, resourceList := range m.results {
+		var kept []resources.ResourceItem
+		for _, item := range resourceList.Items {
+			key := itemKey(resourceList.ResourceType, item)
+			if gone[key] {
+				delete(m.selected, key)
+				continue
+			}
+			kept = append(kept, item)
+		}
+		m.results[i].Items = kept
+	}
+}
+
+// loadManifest fetches the manifest of the item under the cursor once
+func (m *Model) loadManifest() {
+	rows := m.rows()
+	if m.cursor >= len(rows) || rows[m.cursor].kind != itemRow {
+		return
+	}
+	current := rows[m.cursor]
+	key := itemKey(current.resourceType, current.item)
+	if _, ok := m.manifests[key]; ok {
+		return
+	}
+
+	manifest, err := m.backend.Manifest(current.resourceType, current.item)
+	if err != nil {
+		manifest = "Error: " + err.Error()
+	}
+	m.manifests[key] = manifest
+}
+
+// rows returns the visible tree, applying the filter and expansion
+func (m *Model) rows() []row {
+	var rows []row
+	filter := strings.ToLower(m.filter)
+
+	for _, resourceList := range m.results {
+		// Group the matching items by namespace, keeping their order
+		var namespaces []string
+		byNamespace := make(map[string][]resources.ResourceItem)
+		for _, item := range resourceList.Items {
+			if filter != "" && !strings.Contains(strings.ToLower(resourceList.ResourceType+" "+item.Namespace+"/"+item.Name), filter) {
+				continue
+			}
+			if _, ok := byNamespace[item.Namespace]; !ok {
+				namespaces = append(namespaces, item.Namespace)
+			}
+			byNamespace[item.Namespace] = append(byNamespace[item.Namespace], item)
+		}
+		if len(namespaces) == 0 {
+			continue
+		}
+
+		typeIndex := len(rows)
+		rows = append(rows, row{kind: typeRow, resourceType: resourceList.ResourceType})
+		// A filter opens everything it matches
+		typeOpen := m.expanded[resourceList.ResourceType] || filter != ""
+
+		for _, ns := range namespaces {
+			nsRow := row{kind: namespaceRow, resourceType: resourceList.ResourceType, namespace: ns}
+			nsIndex := -1
+			nsOpen := m.expanded[rowKey(nsRow)] || filter != ""
+			if typeOpen {
+				nsIndex = len(rows)
+				rows = append(rows, nsRow)
+			}
+
+			for _, item := range byNamespace[ns] {
+				key := itemKey(resourceList.ResourceType, item)
+				rows[typeIndex].items = append(rows[typeIndex].items, key)
+				if nsIndex >= 0 {
+					rows[nsIndex].items = append(rows[nsIndex].items, key)
+				}
+				if typeOpen && nsOpen {
+					rows = append(rows, row{kind: itemRow, resourceType: resourceList.ResourceType, namespace: ns, item: item, items: []string{key}})
+				}
+			}
+		}
+	}
+
+	return rows
+}
+
+// bodyHeight is the number of tree lines that fit between the title and status lines
+func (m *Model) bodyHeight() int {
+	if m.height < 3 {
+		return 1
+	}
+	return m.height - 2
+}
+
+// clampCursor keeps the cursor on a row
+func (m *Model) clampCursor() {
+	count := len(m.rows())
+	if m.cursor >= count {
+		m.cursor = count - 1
+	}
+	if m.cursor < 0 {
+		m.cursor = 0
+	}
+}
+
+// scroll keeps the cursor within the visible part of the tree
+func (m *Model) scroll() {
+	height := m.bodyHeight()
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	}
+	if m.cursor >= m.offset+height {
+		m.offset = m.cursor - height + 1
+	}
+	if m.offset < 0 {
+		m.offset = 0
+	}
+}
+
+// View renders the screen as lines of at most width columns
+func (m *Model) View() []string {
+	rows := m.rows()
+
+	total := 0
+	for _, resourceList := range m.results {
+		total += len(resourceList.Items)
+	}
+	title := fmt.Sprintf(" k8s-pruner · %d candidates · %d selected", total, len(m.selected))
+	if m.filter != "" {
+		title += fmt.Sprintf(" · filter %q", m.filter)
+	}
+	lines := []string{inverse + bold + fit(title, m.width) + reset}
+
+	// Tree on the left, details of the row under the cursor on the right
+	treeWidth := m.width * 45 / 100
+	detailsWidth := m.width - treeWidth - 3
+	var details []string
+	if m.cursor < len(rows) {
+		details = m.details(rows[m.cursor])
+	}
+	if m.detailsOffset > 0 && m.detailsOffset < len(details) {
+		details = details[m.detailsOffset:]
+	}
+
+	for i := 0; i < m.bodyHeight(); i++ {
+		left := ""
+		index := m.offset + i
+		if index < len(rows) {
+			left = fit(m.rowLabel(rows[index]), treeWidth)
+			if index == m.cursor {
+				left = inverse + left + reset
+			}
+		} else {
+			left = fit("", treeWidth)
+		}
+
+		right := ""
+		if i < len(details) {
+			right = fit(details[i], detailsWidth)
+		}
+		lines = append(lines, left+" │ "+right)
+	}
+
+	status := m.status
+	if m.filtering {
+		status = "/" + m.filter + "▏ (enter to apply, esc to clear)"
+	}
+	lines = append(lines, fit(status, m.width))
+	return lines
+}
+
+// rowLabel formats a tree row with its selection box
+func (m *Model) rowLabel(r row) string {
+	box := "[ ]"
+	switch selected := m.countSelected(r.items); {
+	case selected == len(r.items) && selected > 0:
+		box = "[x]"
+	case selected > 0:
+		box = "[-]"
+	}
+
+	switch r.kind {
+	case typeRow:
+		return fmt.Sprintf("%s %s %s (%d)", arrow(m.expanded[r.resourceType] || m.filter != ""), box, r.resourceType, len(r.items))
+	case namespaceRow:
+		name := r.namespace
+		if name == "" {
+			name = "(cluster)"
+		}
+		return fmt.Sprintf("  %s %s %s (%d)", arrow(m.expanded[rowKey(r)] || m.filter != ""), box, name, len(r.items))
+	default:
+		label := fmt.Sprintf("      %s %s  %s", box, r.item.Name, utils.FormatAge(time.Since(r.item.CreationTimestamp)))
+		if m.referenced[r.items[0]] {
+			label += "  ! referenced"
+		}
+		return label
+	}
+}
+
+// details describes the row under the cursor
+func (m *Model) details(r row) []string {
+	if r.kind != itemRow {
+		name := r.resourceType
+		if r.kind == namespaceRow {
+			name += " in " + r.namespace
+		}
+		return []string{
+			bold + name + reset,
+			"",
+			fmt.Sprintf("%d candidates, %d selected", len(r.items), m.countSelected(r.items)),
+		}
+	}
+
+	item := r.item
+	name := item.Name
+	if item.Namespace != "" {
+		name = item.Namespace + "/" + name
+	}
+	lines := []string{
+		bold + r.resourceType + " " + name + reset,
+		"",
+		"Age:     " + utils.FormatAge(time.Since(item.CreationTimestamp)),
+	}
+	if item.UID != "" {
+		lines = append(lines, "UID:     "+string(item.UID))
+	}
+	if item.Reason != "" {
+		lines = append(lines, "Reason:  "+item.Reason)
+	}
+	if item.CandidateSince != nil {
+		lines = append(lines, "Marked:  "+item.CandidateSince.Format(time.RFC3339))
+	}
+
+	if references, tracked := m.refs.ReferencesTo(r.resourceType, item); tracked {
+		if len(references) == 0 {
+			lines = append(lines, "Referenced by: nothing")
+		} else {
+			lines = append(lines, "", "WARNING: still referenced; deleting it can break", "what references it.", "", "Referenced by:")
+			for _, ref := range references {
+				line := fmt.Sprintf("  %s (%s", ref.From, ref.Via)
+				if ref.Optional {
+					line += ", optional"
+				}
+				lines = append(lines, line+")")
+			}
+		}
+	}
+
+	if !m.showManifest {
+		return append(lines, "", "Press m to show the manifest.")
+	}
+	lines = append(lines, "")
+	manifest := m.manifests[itemKey(r.resourceType, item)]
+	return append(lines, strings.Split(strings.TrimRight(manifest, "\n"), "\n")...)
+}
+
+// countSelected returns how many of keys are selected
+func (m *Model) countSelected(keys []string) int {
+	count := 0
+	for _, key := range keys {
+		if m.selected[key] {
+			count++
+		}
+	}
+	return count
+}
+
+// countReferenced returns how many selected items something references
+func (m *Model) countReferenced() int {
+	count := 0
+	for key := range m.selected {
+		if m.referenced[key] {
+			count++
+		}
+	}
+	return count
+}
+
+// itemKey identifies an item across types
+func itemKey(resourceType string, item resources.ResourceItem) string {
+	return resourceType + "/" + item.Namespace + "/" + item.Name
+}
+
+// rowKey identifies a type or namespace row for expansion
+func rowKey(r row) string {
+	if r.kind == typeRow {
+		return r.resourceType
+	}
+	return r.resourceType + "/" + r.namespace
+}
+
+// arrow shows whether a row is expanded
+func arrow(open bool) string {
+	if open {
+		return "▾"
+	}
+	return "▸"
+}
+
+// fit truncates or pads s to exactly width columns, ignoring ANSI sequences
+func fit(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	columns := 0
+	inEscape := false
+	for _, r := range s {
+		switch {
+		case r == '\x1b':
+			inEscape = true
+		case inEscape:
+			if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' {
+				inEscape = false
+			}
+		default:
+			if columns == width {
+				continue
+			}
+			if r == '\t' {
+				r = ' '
+			}
+			columns++
+		}
+		if columns <= width {
+			b.WriteRune(r)
+		}
+	}
+	return b.String() + strings.Repeat(" ", width-columns)
+}
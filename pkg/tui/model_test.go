@@ -0,0 +1,431 @@
+package tui
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/manthan-parmar-1998/k8s-pruner/pkg/resources"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// Keys as a terminal in raw mode sends them
+const (
+	up    = "\x1b[A"
+	down  = "\x1b[B"
+	left  = "\x1b[D"
+	right = "\x1b[C"
+	enter = "\r"
+	bs    = "\x7f"
+)
+
+// clusterBackend deletes straight through a detector on a fake clientset. It
+// skips the limits, verification and backup the real backend applies; those
+// are tested against that backend in cmd.
+type clusterBackend struct {
+	detector *resources.ResourceDetector
+	deletes  int
+}
+
+func (b *clusterBackend) Manifest(resourceType string, item resources.ResourceItem) (string, error) {
+	return "kind: " + resourceType + "\nname: " + item.Name + "\n", nil
+}
+
+func (b *clusterBackend) Delete(selection []resources.ResourceList) (resources.DeletionReport, error) {
+	b.deletes++
+	return b.detector.DeleteUnusedResources(selection, resources.PruneOptions{}), nil
+}
+
+// newTestModel returns a model browsing the unused ConfigMaps and Secrets of
+// a fake cluster, and the clientset behind it:
+//
+//	ConfigMaps  team-a: old-config, stale-config  team-b: legacy
+//	Secrets     team-a: unused-token
+//
+// team-a/app-config is mounted by a pod and isn't a candidate.
+func newTestModel(t *testing.T) (*Model, *fake.Clientset, *clusterBackend) {
+	t.Helper()
+
+	created := metav1.NewTime(time.Now().Add(-48 * time.Hour))
+	configMap := func(namespace, name string) runtime.Object {
+		return &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, CreationTimestamp: created}}
+	}
+	client := fake.NewSimpleClientset(
+		configMap("team-a", "app-config"),
+		configMap("team-a", "old-config"),
+		configMap("team-a", "stale-config"),
+		configMap("team-b", "legacy"),
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "unused-token", CreationTimestamp: created}, Type: corev1.SecretTypeOpaque},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "web"},
+			Spec: corev1.PodSpec{Volumes: []corev1.Volume{{
+				Name: "config",
+				VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "app-config"},
+				}},
+			}}},
+		},
+	)
+
+	detector := resources.NewResourceDetectorWithClients(resources.Clients{Kube: client})
+	results, err := detector.FindAllUnusedResources("", resources.TimeWindows{}, []string{"configmaps", "secrets"}, "")
+	if err != nil {
+		t.Fatalf("finding candidates: %v", err)
+	}
+	refs, err := detector.References("")
+	if err != nil {
+		t.Fatalf("finding references: %v", err)
+	}
+
+	backend := &clusterBackend{detector: detector}
+	return New(results, refs, backend), client, backend
+}
+
+// press feeds keys to the model through Loop, like a terminal would, and
+// returns what was drawn
+func press(t *testing.T, m *Model, keys string) string {
+	t.Helper()
+
+	var out bytes.Buffer
+	size := func() (int, int) { return 120, 20 }
+	if err := Loop(m, bufio.NewReader(strings.NewReader(keys)), &out, size); err != nil {
+		t.Fatalf("Loop: %v", err)
+	}
+	return out.String()
+}
+
+// labels returns the labels of the visible rows
+func labels(m *Model) []string {
+	var labels []string
+	for _, r := range m.rows() {
+		labels = append(labels, strings.TrimSpace(m.rowLabel(r)))
+	}
+	return labels
+}
+
+// cursorLabel returns the label of the row under the cursor
+func cursorLabel(m *Model) string {
+	return strings.TrimSpace(m.rowLabel(m.rows()[m.cursor]))
+}
+
+// selectedNames returns the selection as "type/namespace/name"
+func selectedNames(m *Model) []string {
+	var names []string
+	for _, resourceList := range m.Selected() {
+		for _, item := range resourceList.Items {
+			names = append(names, itemKey(resourceList.ResourceType, item))
+		}
+	}
+	return names
+}
+
+// equal reports whether a and b hold the same strings in the same order
+func equal(a, b []string) bool {
+	return strings.Join(a, "\n") == strings.Join(b, "\n")
+}
+
+func TestNavigation(t *testing.T) {
+	m, _, _ := newTestModel(t)
+
+	// Types start expanded, namespaces collapsed
+	want := []string{
+		"▾ [ ] ConfigMaps (3)",
+		"▸ [ ] team-a (2)",
+		"▸ [ ] team-b (1)",
+		"▾ [ ] Secrets (1)",
+		"▸ [ ] team-a (1)",
+	}
+	if got := labels(m); !equal(got, want) {
+		t.Fatalf("initial rows:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	// Arrow keys and j/k move, enter expands a namespace
+	press(t, m, down+"j"+up+enter)
+	if got := cursorLabel(m); got != "▾ [ ] team-a (2)" {
+		t.Fatalf("cursor on %q after expanding team-a", got)
+	}
+	press(t, m, down)
+	if got := cursorLabel(m); !strings.Contains(got, "old-config") {
+		t.Fatalf("cursor on %q, want old-config", got)
+	}
+
+	// h moves to the parent and then collapses it
+	press(t, m, "h")
+	if got := cursorLabel(m); got != "▾ [ ] team-a (2)" {
+		t.Fatalf("cursor on %q after h, want team-a", got)
+	}
+	press(t, m, left)
+	if got := cursorLabel(m); got != "▸ [ ] team-a (2)" {
+		t.Fatalf("team-a still open after collapsing: %q", got)
+	}
+
+	// G and g jump to the ends, and the cursor stays on a row
+	press(t, m, "G"+down+down)
+	if got := cursorLabel(m); got != "▸ [ ] team-a (1)" {
+		t.Fatalf("cursor on %q after G, want the Secrets namespace", got)
+	}
+	press(t, m, "g"+up)
+	if m.cursor != 0 {
+		t.Fatalf("cursor at %d after g, want 0", m.cursor)
+	}
+
+	// Right expands too, and the details pane follows the cursor
+	press(t, m, "jj"+right+down)
+	if got := cursorLabel(m); !strings.Contains(got, "legacy") {
+		t.Fatalf("cursor on %q, want legacy", got)
+	}
+	screen := strings.Join(m.View(), "\n")
+	for _, want := range []string{"ConfigMaps team-b/legacy", "Reason:  not referenced by any pod", "Referenced by: nothing"} {
+		if !strings.Contains(screen, want) {
+			t.Errorf("details don't show %q:\n%s", want, screen)
+		}
+	}
+
+	// m shows the manifest from the backend
+	press(t, m, "m")
+	if screen := strings.Join(m.View(), "\n"); !strings.Contains(screen, "name: legacy") {
+		t.Errorf("manifest not shown:\n%s", screen)
+	}
+
+	press(t, m, "q")
+	if !m.Done() {
+		t.Fatal("q didn't quit")
+	}
+}
+
+func TestFilter(t *testing.T) {
+	m, _, _ := newTestModel(t)
+
+	// Typing a filter shows it on the status line until enter applies it
+	press(t, m, "/stala"+bs)
+	if !m.filtering {
+		t.Fatal("not filtering after /")
+	}
+	if status := m.View()[len(m.View())-1]; !strings.Contains(status, "/stal") {
+		t.Fatalf("status line %q doesn't show the filter", status)
+	}
+
+	// A filter opens everything it matches and hides the rest
+	press(t, m, "e"+enter)
+	want := []string{
+		"▾ [ ] ConfigMaps (1)",
+		"▾ [ ] team-a (1)",
+	}
+	got := labels(m)
+	if len(got) != 3 || !equal(got[:2], want) || !strings.Contains(got[2], "stale-config") {
+		t.Fatalf("filtered rows:\n%s", strings.Join(got, "\n"))
+	}
+	if title := m.View()[0]; !strings.Contains(title, `filter "stale"`) {
+		t.Errorf("title %q doesn't show the filter", title)
+	}
+
+	// a selects only what is visible
+	press(t, m, "a")
+	if got := selectedNames(m); !equal(got, []string{"ConfigMaps/team-a/stale-config"}) {
+		t.Fatalf("selected %v with the filter on", got)
+	}
+
+	// The filter matches namespaces and types too, case-insensitively
+	press(t, m, "/"+strings.Repeat(bs, 5)+"SECRETS"+enter)
+	if got := labels(m); len(got) != 3 || !strings.Contains(got[2], "unused-token") {
+		t.Fatalf("rows for SECRETS:\n%s", strings.Join(got, "\n"))
+	}
+
+	// Esc clears it
+	press(t, m, "/\x1b")
+	if m.filter != "" || m.filtering {
+		t.Fatalf("filter %q still set after esc", m.filter)
+	}
+	if got := labels(m); len(got) != 5 {
+		t.Fatalf("rows after clearing the filter:\n%s", strings.Join(got, "\n"))
+	}
+}
+
+func TestMultiSelect(t *testing.T) {
+	m, _, _ := newTestModel(t)
+
+	// Space on a type selects everything in it
+	press(t, m, " ")
+	want := []string{"ConfigMaps/team-a/old-config", "ConfigMaps/team-a/stale-config", "ConfigMaps/team-b/legacy"}
+	if got := selectedNames(m); !equal(got, want) {
+		t.Fatalf("selected %v, want %v", got, want)
+	}
+
+	// Deselecting a namespace leaves the type partly selected
+	press(t, m, "jj ")
+	if got := labels(m); got[0] != "▾ [-] ConfigMaps (3)" || got[2] != "▸ [ ] team-b (1)" {
+		t.Fatalf("rows after deselecting team-b:\n%s", strings.Join(got, "\n"))
+	}
+
+	// Single items toggle on their own, across types
+	press(t, m, "G"+enter+down+" ")
+	want = []string{"ConfigMaps/team-a/old-config", "ConfigMaps/team-a/stale-config", "Secrets/team-a/unused-token"}
+	if got := selectedNames(m); !equal(got, want) {
+		t.Fatalf("selected %v, want %v", got, want)
+	}
+	if title := m.View()[0]; !strings.Contains(title, "4 candidates · 3 selected") {
+		t.Errorf("title %q", title)
+	}
+
+	// A partly selected row selects the rest, and n clears everything
+	press(t, m, "g ")
+	if got := len(m.Selected()[0].Items); got != 3 {
+		t.Fatalf("%d ConfigMaps selected after space on a partly selected type, want 3", got)
+	}
+	press(t, m, "n")
+	if got := selectedNames(m); len(got) != 0 {
+		t.Fatalf("selected %v after n", got)
+	}
+}
+
+func TestReferencedItems(t *testing.T) {
+	m, _, backend := newTestModel(t)
+
+	// A workload that references old-config, as an index built after the
+	// scan can show
+	refs := resources.ReferenceIndex{}
+	target := resources.ObjectRef{Kind: "ConfigMap", Namespace: "team-a", Name: "old-config"}
+	refs[target] = []resources.Reference{{
+		From: resources.ObjectRef{Kind: "Deployment", Namespace: "team-a", Name: "web"},
+		To:   target,
+		Via:  "volume",
+	}}
+	m = New(m.results, refs, backend)
+
+	// Neither a nor a group row selects it
+	press(t, m, "a")
+	want := []string{"ConfigMaps/team-a/stale-config", "ConfigMaps/team-b/legacy", "Secrets/team-a/unused-token"}
+	if got := selectedNames(m); !equal(got, want) {
+		t.Fatalf("selected %v after a, want %v", got, want)
+	}
+	press(t, m, "n ")
+	want = []string{"ConfigMaps/team-a/stale-config", "ConfigMaps/team-b/legacy"}
+	if got := selectedNames(m); !equal(got, want) {
+		t.Fatalf("selected %v after space on the type, want %v", got, want)
+	}
+
+	// The row and details warn about it, and it can still be picked alone
+	press(t, m, down+enter+down)
+	if got := cursorLabel(m); !strings.Contains(got, "old-config") || !strings.HasSuffix(got, "! referenced") {
+		t.Fatalf("cursor on %q, want old-config marked as referenced", got)
+	}
+	screen := strings.Join(m.View(), "\n")
+	for _, want := range []string{"WARNING: still referenced", "Deployment/team-a/web (volume)"} {
+		if !strings.Contains(screen, want) {
+			t.Errorf("details don't show %q:\n%s", want, screen)
+		}
+	}
+	press(t, m, " d")
+	if !strings.Contains(m.status, "Delete 3 resources, 1 still referenced? (y/N)") {
+		t.Fatalf("status %q, want the confirmation to count the referenced item", m.status)
+	}
+	press(t, m, "n")
+}
+
+func TestDelete(t *testing.T) {
+	m, client, backend := newTestModel(t)
+	ctx := context.Background()
+
+	// Nothing selected
+	press(t, m, "d")
+	if m.confirming || !strings.Contains(m.status, "Nothing selected") {
+		t.Fatalf("d without a selection: confirming %v, status %q", m.confirming, m.status)
+	}
+
+	// Anything but y cancels
+	press(t, m, enter+down+" d")
+	if !strings.Contains(m.status, "Delete 2 resources? (y/N)") {
+		t.Fatalf("status %q, want the confirmation", m.status)
+	}
+	press(t, m, "n")
+	if backend.deletes != 0 || m.status != "Delete cancelled." {
+		t.Fatalf("cancelled delete called the backend %d times, status %q", backend.deletes, m.status)
+	}
+
+	// y deletes through the backend
+	press(t, m, "dy")
+	if backend.deletes != 1 {
+		t.Fatalf("backend called %d times, want 1", backend.deletes)
+	}
+	if !strings.HasPrefix(m.status, "Deleted 2 resources, 0 failed, 0 skipped.") {
+		t.Fatalf("status %q", m.status)
+	}
+	for _, name := range []string{"old-config", "stale-config"} {
+		if _, err := client.CoreV1().ConfigMaps("team-a").Get(ctx, name, metav1.GetOptions{}); err == nil {
+			t.Errorf("team-a/%s still exists", name)
+		}
+	}
+	if _, err := client.CoreV1().ConfigMaps("team-a").Get(ctx, "app-config", metav1.GetOptions{}); err != nil {
+		t.Errorf("team-a/app-config was deleted: %v", err)
+	}
+	if _, err := client.CoreV1().ConfigMaps("team-b").Get(ctx, "legacy", metav1.GetOptions{}); err != nil {
+		t.Errorf("unselected team-b/legacy was deleted: %v", err)
+	}
+
+	// Deleted items leave the tree and the selection
+	want := []string{
+		"▾ [ ] ConfigMaps (1)",
+		"▸ [ ] team-b (1)",
+		"▾ [ ] Secrets (1)",
+		"▸ [ ] team-a (1)",
+	}
+	if got := labels(m); !equal(got, want) {
+		t.Fatalf("rows after deleting:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if got := selectedNames(m); len(got) != 0 {
+		t.Fatalf("still selected after deleting: %v", got)
+	}
+}
+
+func TestDeleteKeepsFailures(t *testing.T) {
+	m, client, _ := newTestModel(t)
+
+	// RBAC doesn't allow deleting in team-b
+	client.PrependReactor("delete", "configmaps", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetNamespace() != "team-b" {
+			return false, nil, nil
+		}
+		return true, nil, apierrors.NewForbidden(corev1.Resource("configmaps"), "legacy", fmt.Errorf("not allowed"))
+	})
+
+	press(t, m, " dy")
+	if !strings.Contains(m.status, "Deleted 2 resources, 1 failed") || !strings.Contains(m.status, "team-b/legacy") {
+		t.Fatalf("status %q", m.status)
+	}
+	if got := selectedNames(m); !equal(got, []string{"ConfigMaps/team-b/legacy"}) {
+		t.Fatalf("selected %v, want the failure kept for another try", got)
+	}
+}
+
+func TestLoopDrawsAndQuits(t *testing.T) {
+	m, _, _ := newTestModel(t)
+
+	// Ctrl+C quits and nothing after it is read
+	out := press(t, m, "j\x03jjj")
+	if !m.Done() {
+		t.Fatal("ctrl+c didn't quit")
+	}
+	if m.cursor != 1 {
+		t.Fatalf("cursor at %d, keys after ctrl+c were read", m.cursor)
+	}
+
+	// One frame per key, each drawn from the top of the screen
+	if frames := strings.Count(out, cursorHome); frames != 3 {
+		t.Fatalf("drew %d frames, want 3", frames)
+	}
+	if !strings.Contains(out, "k8s-pruner · 4 candidates · 0 selected") {
+		t.Errorf("title missing from the output")
+	}
+	if strings.Count(out, "\n") != strings.Count(out, "\r\n") {
+		t.Errorf("lines aren't separated by \\r\\n for raw mode")
+	}
+}
@@ -0,0 +1,95 @@
+package tui
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// ANSI sequences used to take over the terminal
+const (
+	enterAltScreen = "\x1b[?1049h"
+	exitAltScreen  = "\x1b[?1049l"
+	hideCursor     = "\x1b[?25l"
+	showCursor     = "\x1b[?25h"
+	cursorHome     = "\x1b[H"
+	clearLine      = "\x1b[K"
+)
+
+// Run shows the model full screen until the user quits, restoring the
+// terminal afterwards
+func Run(m *Model, in *os.File, out *os.File) error {
+	if err := CheckTerminal(in, out); err != nil {
+		return err
+	}
+
+	fd := int(in.Fd())
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return fmt.Errorf("error setting up the terminal: %v", err)
+	}
+	defer term.Restore(fd, state)
+
+	fmt.Fprint(out, enterAltScreen+hideCursor)
+	defer fmt.Fprint(out, showCursor+exitAltScreen)
+
+	size := func() (int, int) {
+		width, height, err := term.GetSize(int(out.Fd()))
+		if err != nil {
+			return 80, 24
+		}
+		return width, height
+	}
+	return Loop(m, bufio.NewReader(in), out, size)
+}
+
+// CheckTerminal fails unless in and out are both a terminal
+func CheckTerminal(in *os.File, out *os.File) error {
+	if !term.IsTerminal(int(in.Fd())) || !term.IsTerminal(int(out.Fd())) {
+		return fmt.Errorf("the TUI needs an interactive terminal")
+	}
+	return nil
+}
+
+// Loop reads keys from in and redraws the model on out after each one until
+// the user quits. size reports the terminal size, which is checked before
+// every redraw so resizing just works on the next key press.
+func Loop(m *Model, in *bufio.Reader, out io.Writer, size func() (int, int)) error {
+	for {
+		m.SetSize(size())
+		if err := Draw(m, out); err != nil {
+			return err
+		}
+		if m.Done() {
+			return nil
+		}
+
+		key, err := ReadKey(in)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		m.Update(key)
+	}
+}
+
+// Draw writes the model's view over the whole screen
+func Draw(m *Model, out io.Writer) error {
+	var b strings.Builder
+	b.WriteString(cursorHome)
+	for i, line := range m.View() {
+		if i > 0 {
+			// Raw mode doesn't translate \n
+			b.WriteString("\r\n")
+		}
+		b.WriteString(line + clearLine)
+	}
+	_, err := io.WriteString(out, b.String())
+	return err
+}
@@ -5,43 +5,174 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/manthan-parmar-1998/k8s-pruner/pkg/resources"
 )
 
-// ParseAge parses an age string like "24h" or "7d" into a time.Time
+// durationUnits maps the units accepted by ParseDuration to their length
+var durationUnits = map[string]time.Duration{
+	"w":  7 * 24 * time.Hour,
+	"d":  24 * time.Hour,
+	"h":  time.Hour,
+	"m":  time.Minute,
+	"s":  time.Second,
+	"ms": time.Millisecond,
+}
+
+// ParseDuration parses a Go-style duration that may also use day (d) and week
+// (w) units, e.g. "90s", "36h", "1d12h" or "1w"
+func ParseDuration(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, fmt.Errorf("empty duration")
+	}
+
+	var total time.Duration
+	rest := value
+	for rest != "" {
+		// Read the number
+		i := 0
+		for i < len(rest) && (rest[i] >= '0' && rest[i] <= '9' || rest[i] == '.') {
+			i++
+		}
+		if i == 0 {
+			return 0, fmt.Errorf("invalid duration %q (use e.g. 90s, 30m, 36h, 1d12h, 2w)", value)
+		}
+		number, err := strconv.ParseFloat(rest[:i], 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q: %v", value, err)
+		}
+		rest = rest[i:]
+
+		// Read the unit
+		j := 0
+		for j < len(rest) && (rest[j] < '0' || rest[j] > '9') && rest[j] != '.' {
+			j++
+		}
+		unit, ok := durationUnits[rest[:j]]
+		if !ok {
+			return 0, fmt.Errorf("invalid duration %q: unknown unit %q (use w, d, h, m, s or ms)", value, rest[:j])
+		}
+		rest = rest[j:]
+
+		total += time.Duration(number * float64(unit))
+	}
+
+	return total, nil
+}
+
+// ParseTime parses an absolute timestamp in RFC 3339 format
+// (2026-01-01T00:00:00Z) or as a plain date (2026-01-01, UTC)
+func ParseTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp %q (use RFC 3339, e.g. 2026-01-01T00:00:00Z)", value)
+}
+
+// ParseAge parses an age like "24h", "1d12h" or "1w" into the cutoff time
+// that many ago. An absolute timestamp is used as the cutoff directly.
 // Returns nil if age is empty
 func ParseAge(age string) (*time.Time, error) {
 	if age == "" {
 		return nil, nil
 	}
 
-	// Parse the age string
-	var duration time.Duration
-	if strings.HasSuffix(age, "h") {
-		// Hours
-		hours, err := strconv.Atoi(strings.TrimSuffix(age, "h"))
+	// Absolute timestamps start with a four digit year
+	if len(age) >= 10 && strings.Count(age[:10], "-") == 2 {
+		cutoff, err := ParseTime(age)
+		if err != nil {
+			return nil, err
+		}
+		return &cutoff, nil
+	}
+
+	duration, err := ParseDuration(age)
+	if err != nil {
+		return nil, err
+	}
+
+	// Calculate the cutoff time
+	cutoff := time.Now().Add(-duration)
+	return &cutoff, nil
+}
+
+// ParseTimeWindows builds the creation time window for each resource type.
+// age is either a single value or a list of per-type thresholds with an
+// optional default, e.g. "7d,jobs=1d,pods=1h,cm=30d". --newer-than
+// and --before apply to every type.
+func ParseTimeWindows(age, newerThan, before string) (resources.TimeWindows, error) {
+	windows := resources.TimeWindows{PerType: map[string]resources.TimeWindow{}}
+
+	defaultAge := ""
+	for _, entry := range strings.Split(age, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		resourceType, value, found := strings.Cut(entry, "=")
+		if !found {
+			if defaultAge != "" {
+				return windows, fmt.Errorf("invalid age %q: more than one default value", age)
+			}
+			defaultAge = entry
+			continue
+		}
+
+		kind, err := resources.LookupKind(resourceType)
 		if err != nil {
-			return nil, fmt.Errorf("invalid hour format: %s", age)
+			return windows, fmt.Errorf("invalid age %q: %v", age, err)
+		}
+		resourceType = kind.Name
+		if _, ok := windows.PerType[resourceType]; ok {
+			return windows, fmt.Errorf("invalid age %q: %s is set more than once", age, resourceType)
 		}
-		duration = time.Duration(hours) * time.Hour
-	} else if strings.HasSuffix(age, "d") {
-		// Days
-		days, err := strconv.Atoi(strings.TrimSuffix(age, "d"))
+
+		window, err := ParseTimeWindow(strings.TrimSpace(value), newerThan, before)
 		if err != nil {
-			return nil, fmt.Errorf("invalid day format: %s", age)
+			return windows, fmt.Errorf("invalid age for %s: %v", resourceType, err)
 		}
-		duration = time.Duration(days) * 24 * time.Hour
-	} else if strings.HasSuffix(age, "m") {
-		// Minutes
-		minutes, err := strconv.Atoi(strings.TrimSuffix(age, "m"))
+		windows.PerType[resourceType] = window
+	}
+
+	var err error
+	windows.Default, err = ParseTimeWindow(defaultAge, newerThan, before)
+	return windows, err
+}
+
+// ParseTimeWindow builds the creation time window from --older-than,
+// --newer-than and --before. Empty values leave that bound open.
+func ParseTimeWindow(olderThan, newerThan, before string) (resources.TimeWindow, error) {
+	var window resources.TimeWindow
+
+	olderCutoff, err := ParseAge(olderThan)
+	if err != nil {
+		return window, err
+	}
+	window.Before = olderCutoff
+
+	if before != "" {
+		beforeTime, err := ParseTime(before)
 		if err != nil {
-			return nil, fmt.Errorf("invalid minute format: %s", age)
+			return window, err
+		}
+		// Both bounds apply, so keep the earlier one
+		if window.Before == nil || beforeTime.Before(*window.Before) {
+			window.Before = &beforeTime
 		}
-		duration = time.Duration(minutes) * time.Minute
-	} else {
-		return nil, fmt.Errorf("unsupported age format: %s (use h for hours, d for days, m for minutes)", age)
 	}
 
-	// Calculate the cutoff time
-	cutoff := time.Now().Add(-duration)
-	return &cutoff, nil
+	window.After, err = ParseAge(newerThan)
+	if err != nil {
+		return window, err
+	}
+
+	if window.After != nil && window.Before != nil && !window.After.Before(*window.Before) {
+		return window, fmt.Errorf("empty time window: --newer-than must reach back further than --older-than and --before")
+	}
+
+	return window, nil
 }
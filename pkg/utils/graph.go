@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/manthan-parmar-1998/k8s-pruner/pkg/resources"
+)
+
+// OutputGraph outputs the reference graph in the specified format: dot
+// (the default), mermaid, json or yaml
+func OutputGraph(graph *resources.Graph, format string) error {
+	if ok, err := outputData(graph, format); ok {
+		return err
+	}
+
+	switch strings.ToLower(format) {
+	case "mermaid":
+		fmt.Print(formatMermaid(graph))
+		return nil
+	case "dot", "text":
+		fmt.Print(formatDOT(graph))
+		return nil
+	default:
+		return fmt.Errorf("unsupported output format %q for graph, use dot, mermaid, json or yaml", format)
+	}
+}
+
+// formatDOT renders the graph for Graphviz. Candidates are drawn dashed in
+// red and missing objects dotted in grey.
+func formatDOT(graph *resources.Graph) string {
+	var b strings.Builder
+	b.WriteString("digraph references {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box];\n")
+
+	for _, node := range graph.Nodes {
+		attrs := []string{"label=" + dotQuote(nodeLabel(node, "\\n"))}
+		switch {
+		case node.Missing:
+			attrs = append(attrs, "style=dotted", "color=grey")
+		case node.Candidate:
+			attrs = append(attrs, "style=dashed", "color=red")
+		}
+		fmt.Fprintf(&b, "  %s [%s];\n", dotQuote(node.String()), strings.Join(attrs, ", "))
+	}
+
+	for _, edge := range graph.Edges {
+		fmt.Fprintf(&b, "  %s -> %s [label=%s];\n", dotQuote(edge.From.String()), dotQuote(edge.To.String()), dotQuote(edgeLabel(edge)))
+	}
+
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// formatMermaid renders the graph as a Mermaid flowchart, with the same
+// styling as formatDOT
+func formatMermaid(graph *resources.Graph) string {
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+
+	// Mermaid IDs can't contain slashes, so number the nodes
+	ids := make(map[resources.ObjectRef]string)
+	var candidates, missing []string
+	for i, node := range graph.Nodes {
+		id := fmt.Sprintf("n%d", i)
+		ids[node.ObjectRef] = id
+		fmt.Fprintf(&b, "  %s[\"%s\"]\n", id, strings.ReplaceAll(nodeLabel(node, "<br/>"), `"`, "#quot;"))
+
+		switch {
+		case node.Missing:
+			missing = append(missing, id)
+		case node.Candidate:
+			candidates = append(candidates, id)
+		}
+	}
+
+	for _, edge := range graph.Edges {
+		fmt.Fprintf(&b, "  %s -->|\"%s\"| %s\n", ids[edge.From], edgeLabel(edge), ids[edge.To])
+	}
+
+	if len(candidates) > 0 {
+		b.WriteString("  classDef candidate stroke:#d33,stroke-dasharray:5 5\n")
+		fmt.Fprintf(&b, "  class %s candidate\n", strings.Join(candidates, ","))
+	}
+	if len(missing) > 0 {
+		b.WriteString("  classDef missing stroke:#999,stroke-dasharray:2 2\n")
+		fmt.Fprintf(&b, "  class %s missing\n", strings.Join(missing, ","))
+	}
+	return b.String()
+}
+
+// dotQuote quotes s as a DOT string, keeping escapes such as \n intact
+func dotQuote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
+
+// nodeLabel returns the kind and namespace/name of a node on two lines
+func nodeLabel(node resources.GraphNode, lineBreak string) string {
+	name := node.Name
+	if node.Namespace != "" {
+		name = node.Namespace + "/" + node.Name
+	}
+
+	label := node.Kind + lineBreak + name
+	if node.Missing {
+		label += lineBreak + "(missing)"
+	}
+	return label
+}
+
+// edgeLabel returns how an edge's reference is made
+func edgeLabel(edge resources.Reference) string {
+	if edge.Optional {
+		return edge.Via + " (optional)"
+	}
+	return edge.Via
+}
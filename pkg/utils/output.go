@@ -7,24 +7,23 @@ import (
 	"time"
 
 	"github.com/manthan-parmar-1998/k8s-pruner/pkg/resources"
-	"gopkg.in/yaml.v2"
+	rbacv1 "k8s.io/api/rbac/v1"
+	sigsyaml "sigs.k8s.io/yaml"
 )
 
-// OutputResults outputs the results in the specified format
+// OutputResults outputs the results in the specified format. JSON and YAML
+// are a versioned PruneReport, which is empty rather than absent when
+// nothing was found.
 func OutputResults(results []resources.ResourceList, format string, header string) error {
+	if ok, err := outputData(resources.NewPruneReport(results), format); ok {
+		return err
+	}
+
 	if len(results) == 0 {
 		fmt.Println("No unused resources found.")
 		return nil
 	}
-
-	switch strings.ToLower(format) {
-	case "json":
-		return outputJSON(results)
-	case "yaml":
-		return outputYAML(results)
-	default:
-		return outputText(results, header)
-	}
+	return outputText(results, header)
 }
 
 // outputText outputs results in human-readable text format
@@ -41,7 +40,7 @@ func outputText(results []resources.ResourceList, header string) error {
 		fmt.Println(strings.Repeat("-", len(resourceList.ResourceType)+1))
 
 		for _, item := range resourceList.Items {
-			age := formatAge(time.Since(item.Age))
+			age := FormatAge(time.Since(item.CreationTimestamp))
 			fmt.Printf("  %s/%s (age: %s)\n", item.Namespace, item.Name, age)
 			totalCount++
 		}
@@ -51,9 +50,21 @@ func outputText(results []resources.ResourceList, header string) error {
 	return nil
 }
 
-// outputJSON outputs results in JSON format
-func outputJSON(results []resources.ResourceList) error {
-	jsonData, err := json.MarshalIndent(results, "", "  ")
+// outputData outputs v as JSON or YAML if format is either, and reports
+// whether it did
+func outputData(v interface{}, format string) (bool, error) {
+	switch strings.ToLower(format) {
+	case "json":
+		return true, outputJSON(v)
+	case "yaml":
+		return true, outputYAML(v)
+	}
+	return false, nil
+}
+
+// outputJSON outputs v in JSON format
+func outputJSON(v interface{}) error {
+	jsonData, err := json.MarshalIndent(v, "", "  ")
 	if err != nil {
 		return fmt.Errorf("error marshaling to JSON: %v", err)
 	}
@@ -62,19 +73,217 @@ func outputJSON(results []resources.ResourceList) error {
 	return nil
 }
 
-// outputYAML outputs results in YAML format
-func outputYAML(results []resources.ResourceList) error {
-	yamlData, err := yaml.Marshal(results)
+// outputYAML outputs v in YAML format, with the same field names as the JSON
+func outputYAML(v interface{}) error {
+	yamlData, err := sigsyaml.Marshal(v)
 	if err != nil {
 		return fmt.Errorf("error marshaling to YAML: %v", err)
 	}
 
-	fmt.Println(string(yamlData))
+	fmt.Print(string(yamlData))
+	return nil
+}
+
+// OutputDeletionReport outputs the outcome of a prune in the specified format
+func OutputDeletionReport(report resources.DeletionReport, format string) error {
+	if ok, err := outputData(report, format); ok {
+		return err
+	}
+	return outputDeletionReportText(report)
+}
+
+// outputDeletionReportText outputs the outcome of a prune in human-readable text format
+func outputDeletionReportText(report resources.DeletionReport) error {
+	deleted := report.Count(resources.StatusDeleted)
+	notFound := report.Count(resources.StatusNotFound)
+	skipped := report.Count(resources.StatusSkipped)
+	failed := report.Failed()
+
+	// Only list every item when something didn't go to plan, or for a
+	// dry run, where the per-item outcome is the point
+	if failed > 0 || skipped > 0 || report.DryRun {
+		fmt.Println("\nDeletion results:")
+		resourceType := ""
+		for _, result := range report.Results {
+			if result.ResourceType != resourceType {
+				resourceType = result.ResourceType
+				fmt.Printf("\n%s:\n", resourceType)
+				fmt.Println(strings.Repeat("-", len(resourceType)+1))
+			}
+
+			status := string(result.Status)
+			if report.DryRun && result.Status == resources.StatusDeleted {
+				status = "would delete"
+			}
+
+			line := fmt.Sprintf("  %-12s %s/%s", status, result.Namespace, result.Name)
+			if result.Reason != "" {
+				line += ": " + result.Reason
+			}
+			if result.Error != "" {
+				line += ": " + result.Error
+			}
+			fmt.Println(line)
+		}
+		fmt.Println()
+	}
+
+	if report.DryRun {
+		fmt.Printf("DRY RUN (server): %d resources would be pruned.\n", deleted+notFound)
+	} else if notFound > 0 {
+		fmt.Printf("Successfully pruned %d resources (%d were already gone).\n", deleted+notFound, notFound)
+	} else {
+		fmt.Printf("Successfully pruned %d resources.\n", deleted)
+	}
+	if skipped > 0 {
+		fmt.Printf("Skipped %d resources.\n", skipped)
+	}
+	if failed > 0 {
+		if report.DryRun {
+			fmt.Printf("%d resources would fail to prune.\n", failed)
+		} else {
+			fmt.Printf("Failed to prune %d resources.\n", failed)
+		}
+	}
+	return nil
+}
+
+// OutputMarkReport outputs the marks set and cleared by mark in the specified format
+func OutputMarkReport(report resources.MarkReport, format string, dryRun bool) error {
+	if ok, err := outputData(report, format); ok {
+		return err
+	}
+	return outputMarkReportText(report, dryRun)
+}
+
+// outputMarkReportText outputs the marks set and cleared in human-readable text format
+func outputMarkReportText(report resources.MarkReport, dryRun bool) error {
+	if len(report.Results) == 0 {
+		fmt.Println("No marks to set or clear.")
+		return nil
+	}
+
+	marked, cleared := 0, 0
+	resourceType := ""
+	for _, result := range report.Results {
+		if result.ResourceType != resourceType {
+			resourceType = result.ResourceType
+			fmt.Printf("\n%s:\n", resourceType)
+			fmt.Println(strings.Repeat("-", len(resourceType)+1))
+		}
+
+		line := fmt.Sprintf("  %-8s %s/%s", result.Action, result.Namespace, result.Name)
+		if result.Error != "" {
+			line += ": " + result.Error
+		} else if result.Action == "marked" {
+			marked++
+		} else {
+			cleared++
+		}
+		fmt.Println(line)
+	}
+
+	fmt.Printf("\nMarked %d resources, cleared %d marks.\n", marked, cleared)
+	if dryRun {
+		fmt.Println("DRY RUN: No resources were changed.")
+	}
+	if failed := report.Failed(); failed > 0 {
+		fmt.Printf("Failed to update %d resources.\n", failed)
+	}
 	return nil
 }
 
-// formatAge formats a duration into a human-readable string
-func formatAge(d time.Duration) string {
+// OutputExplanation outputs why a resource is or isn't a candidate in the specified format
+func OutputExplanation(explanation *resources.Explanation, format string) error {
+	if ok, err := outputData(explanation, format); ok {
+		return err
+	}
+	return outputExplanationText(explanation)
+}
+
+// outputExplanationText outputs the checks, references and verdict in human-readable text format
+func outputExplanationText(explanation *resources.Explanation) error {
+	name := explanation.Name
+	if explanation.Namespace != "" {
+		name = explanation.Namespace + "/" + explanation.Name
+	}
+	fmt.Printf("%s %s (age: %s)\n", explanation.ResourceType, name, FormatAge(time.Since(explanation.CreationTimestamp)))
+
+	fmt.Println("\nChecks:")
+	for _, check := range explanation.Checks {
+		result := "pass"
+		if !check.Passed {
+			result = "FAIL"
+		}
+		fmt.Printf("  %s  %-11s %s\n", result, check.Name, check.Detail)
+	}
+
+	fmt.Println("\nReferences:")
+	switch {
+	case !explanation.ReferencesTracked:
+		fmt.Printf("  not tracked for %s\n", explanation.ResourceType)
+	case len(explanation.References) == 0:
+		fmt.Println("  none")
+	}
+	for _, ref := range explanation.References {
+		line := fmt.Sprintf("  %s via %s", ref.From, ref.Via)
+		if ref.Optional {
+			line += " (optional)"
+		}
+		fmt.Println(line)
+	}
+
+	fmt.Printf("\nVerdict: %s\n", explanation.Verdict)
+	return nil
+}
+
+// OutputDanglingReferences outputs references to missing objects in the specified format
+func OutputDanglingReferences(refs []resources.Reference, format string) error {
+	if ok, err := outputData(refs, format); ok {
+		return err
+	}
+	return outputDanglingReferencesText(refs)
+}
+
+// outputDanglingReferencesText outputs references grouped by missing object in human-readable text format
+func outputDanglingReferencesText(refs []resources.Reference) error {
+	if len(refs) == 0 {
+		fmt.Println("No dangling references found.")
+		return nil
+	}
+
+	fmt.Println("Found references to the following missing resources:")
+
+	missing := 0
+	var target resources.ObjectRef
+	for i, ref := range refs {
+		if i == 0 || ref.To != target {
+			target = ref.To
+			missing++
+			fmt.Printf("\n%s:\n", target)
+		}
+
+		line := fmt.Sprintf("  %s via %s", ref.From, ref.Via)
+		if ref.Optional {
+			line += " (optional)"
+		}
+		fmt.Println(line)
+	}
+
+	fmt.Printf("\nTotal: %d references to %d missing resources\n", len(refs), missing)
+	return nil
+}
+
+// OutputClusterRole outputs a ClusterRole as YAML, or as JSON with -o json
+func OutputClusterRole(role *rbacv1.ClusterRole, format string) error {
+	if strings.ToLower(format) == "json" {
+		return outputJSON(role)
+	}
+	return outputYAML(role)
+}
+
+// FormatAge formats a duration into a human-readable string
+func FormatAge(d time.Duration) string {
 	d = d.Round(time.Minute)
 
 	days := d / (24 * time.Hour)
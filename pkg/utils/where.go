@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/manthan-parmar-1998/k8s-pruner/pkg/policy"
+	"github.com/manthan-parmar-1998/k8s-pruner/pkg/resources"
+)
+
+// typedWhere matches "type=expression". CEL has no single "=" operator, so
+// this can't be mistaken for an expression.
+var typedWhere = regexp.MustCompile(`^\s*([A-Za-z0-9-]+)\s*=([^=].*)$`)
+
+// ParseWhere compiles --where values. Each is an expression for all types,
+// or "type=expression" for a single type, e.g.
+// `secrets=object.type == "Opaque" && size > 100000`.
+func ParseWhere(values []string) (policy.Filters, error) {
+	var filters policy.Filters
+
+	for _, value := range values {
+		resourceType, expression := "", value
+		if match := typedWhere.FindStringSubmatch(value); match != nil {
+			resourceType, expression = match[1], match[2]
+		}
+
+		if err := AddFilter(&filters, resourceType, expression); err != nil {
+			return filters, err
+		}
+	}
+
+	return filters, nil
+}
+
+// AddFilter compiles expression and adds it to filters for resourceType,
+// which may be any name known to resources.LookupKind, or "" for all types
+func AddFilter(filters *policy.Filters, resourceType, expression string) error {
+	if resourceType != "" {
+		kind, err := resources.LookupKind(resourceType)
+		if err != nil {
+			return fmt.Errorf("invalid --where %q: %v", expression, err)
+		}
+		resourceType = kind.Name
+	}
+
+	compiled, err := policy.Compile(expression)
+	if err != nil {
+		return err
+	}
+
+	filters.Add(resourceType, compiled)
+	return nil
+}